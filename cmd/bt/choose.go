@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+type chooser struct {
+	toStdout bool
+	file     string
+	nul      bool
+}
+
+func (c chooser) enabled() bool {
+	return c.toStdout || c.file != ""
+}
+
+// When chosen paths go to stdout, UI is rendered to the terminal directly.
+func (c chooser) programOptions() ([]tea.ProgramOption, func(), error) {
+	if !c.toStdout {
+		return nil, func() {}, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Colors are detected for the terminal, not for the stdout pipe.
+	lipgloss.DefaultRenderer().SetOutput(termenv.NewOutput(tty))
+	opts := []tea.ProgramOption{tea.WithInput(tty), tea.WithOutput(tty)}
+	return opts, func() { tty.Close() }, nil
+}
+
+func (c chooser) write(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	sep := "\n"
+	if c.nul {
+		sep = "\x00"
+	}
+	out := strings.Join(paths, sep) + sep
+
+	var w io.Writer = os.Stdout
+	if c.file != "" {
+		f, err := os.Create(c.file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	flag "github.com/spf13/pflag"
@@ -24,6 +25,7 @@ func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		listenFSEvents(m.appState.NodeChanges),
 		listenPreviewReady(m.renderer.PreviewDoneChan),
+		m.appState.RefreshGitStatus(),
 	)
 }
 
@@ -31,15 +33,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.window = ui.Dimentions{Height: msg.Height, Width: msg.Width}
+		m.appState.WindowHeight = msg.Height
 	case tea.KeyMsg:
 		return m, m.appState.ProcessKey(msg)
-	case tree.NodeChange:
+	case state.NodeChange:
 		m.renderer.RemovePreviewCache(msg.Path)
-		m.appState.ProcessNodeChange(msg)
-		return m, listenFSEvents(m.appState.NodeChanges)
+		m.renderer.RemovePreviewCache(filepath.Dir(msg.Path)) // listing of the parent
+		cmd := m.appState.ProcessNodeChange(msg)
+		return m, tea.Batch(cmd, listenFSEvents(m.appState.NodeChanges))
 	case ui.Preview:
 		m.renderer.SetPreviewCache(msg)
+		m.appState.ClampPreviewOffset(msg.Path, msg.MaxOffset)
 		return m, listenPreviewReady(m.renderer.PreviewDoneChan)
+	case tree.FindBatch:
+		return m, m.appState.ProcessFindBatch(msg)
+	case state.GitStatusMsg:
+		return m, m.appState.ProcessGitStatus(msg)
 	}
 	return m, nil
 }
@@ -48,24 +57,33 @@ func (m model) View() string {
 }
 
 func newModel(
-	root string,
+	roots []string,
 	style ui.Stylesheet,
-	padding int,
-	filePreview bool,
-	highlightCurrentIndent bool,
+	conf config.BtConfig,
 ) (model, error) {
-	s, err := state.InitState(root)
+	s, err := state.InitState(roots, conf)
 	if err != nil {
 		return model{}, err
 	}
-	renderer := ui.NewRenderer(style, padding, filePreview, highlightCurrentIndent)
+	if conf.SyntaxTheme != "" {
+		style.SyntaxTheme = conf.SyntaxTheme
+	}
+	if conf.TabWidth > 0 {
+		style.TabWidth = conf.TabWidth
+	}
+	images, err := ui.ParseImageProtocol(conf.ImageProtocol)
+	if err != nil {
+		s.Close()
+		return model{}, err
+	}
+	renderer := ui.NewRenderer(style, conf.Padding, conf.FilePreview, conf.HighlightIndent, images)
 	return model{
 		appState: s,
 		renderer: renderer,
 	}, nil
 }
 
-func listenFSEvents(eventsChan <-chan tree.NodeChange) tea.Cmd {
+func listenFSEvents(eventsChan <-chan state.NodeChange) tea.Cmd {
 	return func() tea.Msg {
 		return <-eventsChan
 	}
@@ -78,40 +96,86 @@ func listenPreviewReady(previewChan <-chan ui.Preview) tea.Cmd {
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// Errors go to stderr, as stdout carries chosen paths in choose mode.
+func run() int {
 	flag.UintP("padding", "p", 5, "Edge padding for top and bottom")
 	flag.BoolP("in_place_render", "i", false, "In-place render (without alternate screen)")
 	flag.Bool("file_preview", true, "Enable file previews")
 	flag.Bool("highlight_indent", true, "Highlight current indent")
+	flag.Uint("tab_width", 4, "Width of tabs in file previews")
+	flag.Bool("search_unread_dirs", false, "Search inside directories, that were not opened yet")
+	flag.Uint("find_depth_limit", 16, "Maximum directory depth for find")
+	flag.Bool("respect_gitignore", false, "Hide files, ignored by .gitignore / .ignore (toggled with I)")
+
+	var choose chooser
+	flag.BoolVar(&choose.toStdout, "choose", false, "Print chosen paths to stdout on exit, rendering UI to /dev/tty")
+	flag.StringVar(&choose.file, "choose-file", "", "Write chosen paths to `PATH` on exit")
+	flag.BoolVar(&choose.nul, "choose-nul", false, "Separate chosen paths with NUL instead of newline")
+
+	lastDirFile := flag.String("last-dir-file", "", "Write last visited directory to `PATH` on exit (skipped when quitting with Q)")
+	initShell := flag.String("init", "", "Print `SHELL` (bash, zsh, fish) function, that cds to the last visited directory on exit")
 
 	flag.Parse()
 
+	if *initShell != "" {
+		script, err := shellInit(*initShell)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(script)
+		return 0
+	}
+
 	conf := config.GetConfig(flag.CommandLine)
 
-	rootPath := flag.Arg(0)
-	if rootPath == "" {
-		rootPath = "."
+	roots := flag.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
 	}
 
-	m, err := newModel(
-		rootPath,
-		ui.DefaultStylesheet,
-		conf.Padding,
-		conf.FilePreview,
-		conf.HighlightIndent,
-	)
+	m, err := newModel(roots, ui.DefaultStylesheet, conf)
 	if err != nil {
-		fmt.Printf("Error on init: %v", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error on init: %v\n", err)
+		return 1
 	}
+	// Remote roots keep ssh connections open until exit.
+	defer m.appState.Close()
 
-	opts := []tea.ProgramOption{}
+	m.appState.ChooseMode = choose.enabled()
+
+	opts, closeTTY, err := choose.programOptions()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error on init: %v\n", err)
+		return 1
+	}
+	defer closeTTY()
 	if !conf.InPlaceRender {
 		opts = append(opts, tea.WithAltScreen())
 	}
 
 	p := tea.NewProgram(m, opts...)
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Error: %v", err)
-		os.Exit(1)
+	final, err := p.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	finalState := final.(model).appState
+	// Shell can't change into remote directories.
+	if *lastDirFile != "" && !finalState.SkipLastDir && finalState.Tree.CurrentDir.IsLocal() {
+		if err := writeLastDir(*lastDirFile, finalState.Tree.CurrentDir.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if choose.enabled() {
+		if err := choose.write(finalState.Chosen); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
 	}
+	return 0
 }
@@ -6,13 +6,23 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
+	"github.com/LeperGnome/bt/internal/config"
 	"github.com/LeperGnome/bt/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/x/exp/teatest"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
+var testConfig = config.BtConfig{
+	Padding:         5,
+	FilePreview:     true,
+	HighlightIndent: true,
+	FindDepthLimit:  16,
+}
+
 type BtTestSuite struct {
 	suite.Suite
 	m  model
@@ -29,7 +39,7 @@ func (s *BtTestSuite) SetupTest() {
 	_, err = f.WriteString("some text")
 	s.Require().NoError(err)
 
-	m, err := newModel(dir, ui.DefaultStylesheet, 5, true, true)
+	m, err := newModel([]string{dir}, ui.DefaultStylesheet, testConfig)
 	s.Require().NoError(err)
 
 	tm := teatest.NewTestModel(s.T(), m, teatest.WithInitialTermSize(100, 100))
@@ -60,6 +70,89 @@ func (s *BtTestSuite) TestExample() {
 	})
 }
 
+func (s *BtTestSuite) TestFind() {
+	s.tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	s.tm.Type("tfile")
+	teatest.WaitFor(s.T(), s.tm.Output(), func(out []byte) bool {
+		return bytes.Contains(out, []byte("1/1"))
+	})
+}
+
+func TestChooseMode(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(path.Join(dir, "chosen.txt"))
+	require.NoError(t, err)
+	f.Close()
+
+	m, err := newModel([]string{dir}, ui.DefaultStylesheet, testConfig)
+	require.NoError(t, err)
+	m.appState.ChooseMode = true
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 100))
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(model)
+	require.Equal(t, []string{path.Join(dir, "chosen.txt")}, final.appState.Chosen)
+}
+
+func TestChooseModeAbort(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(path.Join(dir, "marked.txt"))
+	require.NoError(t, err)
+	f.Close()
+
+	m, err := newModel([]string{dir}, ui.DefaultStylesheet, testConfig)
+	require.NoError(t, err)
+	m.appState.ChooseMode = true
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 100))
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab})
+	tm.Send(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(model)
+	require.Len(t, final.appState.Tree.Marked, 1)
+	require.Empty(t, final.appState.Chosen)
+}
+
+func TestQuitSkipsLastDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(dir, "subdir"), os.ModePerm))
+
+	m, err := newModel([]string{dir}, ui.DefaultStylesheet, testConfig)
+	require.NoError(t, err)
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 100))
+	tm.Type("lQ")
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(model)
+	require.True(t, final.appState.SkipLastDir)
+	require.Equal(t, path.Join(dir, "subdir"), final.appState.Tree.CurrentDir.Path)
+}
+
+func TestCopyBetweenRoots(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(src, "copied.txt"), []byte("content"), 0o644))
+	require.NoError(t, os.WriteFile(path.Join(dst, "existing.txt"), nil, 0o644))
+
+	m, err := newModel([]string{src, dst}, ui.DefaultStylesheet, testConfig)
+	require.NoError(t, err)
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 100))
+	tm.Type("ytp")
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		return bytes.Contains(out, []byte("[2/2]"))
+	})
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(path.Join(dst, "copied.txt"))
+		return err == nil && string(content) == "content"
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, tm.Quit())
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(model)
+	require.Equal(t, dst, final.appState.Tree.Root.Path)
+	require.FileExists(t, path.Join(src, "copied.txt"))
+}
+
 func TestBtTestSuite(t *testing.T) {
 	suite.Run(t, new(BtTestSuite))
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Shell functions, which start bt and cd to the last visited directory on exit.
+// Quitting with Q leaves current directory unchanged.
+const (
+	posixShellInit = `btcd() {
+    local tmp dir
+    tmp="$(mktemp)"
+    command bt --last-dir-file="$tmp" "$@"
+    if [ -s "$tmp" ]; then
+        dir="$(cat "$tmp")"
+        if [ -d "$dir" ] && [ "$dir" != "$PWD" ]; then
+            cd "$dir" || return
+        fi
+    fi
+    rm -f "$tmp"
+}
+`
+	fishShellInit = `function btcd
+    set -l tmp (mktemp)
+    command bt --last-dir-file=$tmp $argv
+    if test -s $tmp
+        set -l dir (cat $tmp)
+        if test -d "$dir"; and test "$dir" != "$PWD"
+            cd $dir
+        end
+    end
+    rm -f $tmp
+end
+`
+)
+
+func shellInit(shell string) (string, error) {
+	switch shell {
+	case "bash", "zsh":
+		return posixShellInit, nil
+	case "fish":
+		return fishShellInit, nil
+	default:
+		return "", fmt.Errorf("unsupported shell '%s', expected one of: bash, zsh, fish", shell)
+	}
+}
+
+func writeLastDir(file, dir string) error {
+	return os.WriteFile(file, []byte(dir+"\n"), 0o600)
+}
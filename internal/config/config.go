@@ -8,10 +8,19 @@ import (
 )
 
 type BtConfig struct {
-	Padding         int  `mapstructure:"padding"`
-	FilePreview     bool `mapstructure:"file_preview"`
-	HighlightIndent bool `mapstructure:"highlight_indent"`
-	InPlaceRender   bool `mapstructure:"in_place_render"`
+	Padding         int    `mapstructure:"padding"`
+	FilePreview     bool   `mapstructure:"file_preview"`
+	HighlightIndent bool   `mapstructure:"highlight_indent"`
+	InPlaceRender   bool   `mapstructure:"in_place_render"`
+	SyntaxTheme     string `mapstructure:"syntax_theme"`
+	ImageProtocol   string `mapstructure:"image_protocol"`
+	TabWidth        int    `mapstructure:"tab_width"`
+
+	SearchUnreadDirs bool `mapstructure:"search_unread_dirs"`
+	FindDepthLimit   int  `mapstructure:"find_depth_limit"`
+	RespectGitignore bool `mapstructure:"respect_gitignore"`
+
+	Keys map[string][]string `mapstructure:"keys"` // action name -> key sequences
 }
 
 func GetConfig(flags *pflag.FlagSet) BtConfig {
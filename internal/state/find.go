@@ -0,0 +1,183 @@
+package state
+
+import (
+	"path/filepath"
+	"slices"
+
+	t "github.com/LeperGnome/bt/internal/tree"
+	"github.com/LeperGnome/bt/pkg/fuzzy"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const findResultsLimit = 100_000
+
+type FindMatch struct {
+	Result    t.FindResult
+	RelPath   string
+	Positions []int // indices of matched runes in RelPath
+
+	score int
+}
+
+// FindState holds results of a background file finder.
+type FindState struct {
+	Matches  []FindMatch
+	Selected int
+	Done     bool
+	Total    int
+
+	root    string
+	query   string
+	results []FindMatch
+	finder  *t.Finder
+}
+
+func (f *FindState) SelectedMatch() (FindMatch, bool) {
+	if len(f.Matches) == 0 {
+		return FindMatch{}, false
+	}
+	return f.Matches[f.Selected], true
+}
+func (f *FindState) selectNext() {
+	f.Selected = min(f.Selected+1, max(len(f.Matches)-1, 0))
+}
+func (f *FindState) selectPrevious() {
+	f.Selected = max(f.Selected-1, 0)
+}
+func (f *FindState) add(results []t.FindResult) {
+	added := len(f.results)
+	for _, res := range results {
+		if len(f.results) >= findResultsLimit {
+			break
+		}
+		rel, err := filepath.Rel(f.root, res.Path)
+		if err != nil {
+			continue
+		}
+		f.results = append(f.results, FindMatch{Result: res, RelPath: rel})
+	}
+	f.Total = len(f.results)
+	// Only a new batch is scored, so streaming stays linear in number of results.
+	f.Matches = f.merge(f.Matches, f.match(f.results[added:]))
+	f.Selected = max(min(f.Selected, len(f.Matches)-1), 0)
+}
+func (f *FindState) filter(query string) {
+	f.query = query
+	f.Matches = f.match(f.results)
+	f.Selected = max(min(f.Selected, len(f.Matches)-1), 0)
+}
+
+// Scores results with current query. Returned matches are sorted by score.
+func (f *FindState) match(results []FindMatch) []FindMatch {
+	matches := []FindMatch{}
+	for _, res := range results {
+		score, positions, ok := fuzzy.Match(f.query, res.RelPath)
+		if !ok {
+			continue
+		}
+		res.score = score
+		res.Positions = positions
+		matches = append(matches, res)
+	}
+	if f.query != "" {
+		slices.SortStableFunc(matches, func(a, b FindMatch) int { return b.score - a.score })
+	}
+	return matches
+}
+
+// Merges sorted matches, keeping earlier results first among equal scores.
+func (f *FindState) merge(old, added []FindMatch) []FindMatch {
+	if f.query == "" || len(added) == 0 {
+		return append(old, added...)
+	}
+	merged := make([]FindMatch, 0, len(old)+len(added))
+	i, j := 0, 0
+	for i < len(old) && j < len(added) {
+		if old[i].score >= added[j].score {
+			merged = append(merged, old[i])
+			i++
+		} else {
+			merged = append(merged, added[j])
+			j++
+		}
+	}
+	merged = append(merged, old[i:]...)
+	return append(merged, added[j:]...)
+}
+
+func (s *State) startFind() tea.Cmd {
+	s.stopFind()
+	finder := s.Tree.StartFind(s.findDepthLimit)
+	s.Find = &FindState{
+		root:   s.Tree.Root.Path,
+		finder: finder,
+	}
+	s.InputBuf = []rune{}
+	s.OpBuf = Find
+	return listenFind(finder.Batches)
+}
+func (s *State) stopFind() {
+	if s.Find != nil {
+		s.Find.finder.Stop()
+		s.Find = nil
+	}
+}
+
+// Adds results, streamed by a finder. Returns command to wait for the next batch.
+func (s *State) ProcessFindBatch(batch t.FindBatch) tea.Cmd {
+	if s.Find == nil || s.Find.finder.ID != batch.FinderID {
+		// Candidates of a deep search, walked in background.
+		for _, tree := range s.Trees {
+			if tree.AddSearchCandidates(batch) {
+				if batch.Done {
+					return nil
+				}
+				return listenFind(tree.Search.Finder.Batches)
+			}
+		}
+		// Results of a stopped finder, it's channel will be drained and closed.
+		return nil
+	}
+	s.Find.add(batch.Results)
+	if batch.Done {
+		s.Find.Done = true
+		return nil
+	}
+	return listenFind(s.Find.finder.Batches)
+}
+
+func (s *State) processKeyFind(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter":
+		if m, ok := s.Find.SelectedMatch(); ok {
+			if err := s.Tree.RevealPath(m.Result.Path); err != nil {
+				s.ErrBuf = err.Error()
+			}
+		}
+		s.stopFind()
+		s.OpBuf = Noop
+		s.InputBuf = []rune{}
+	case "ctrl+c", "esc":
+		s.stopFind()
+		return s.processKeyAnyInput(msg)
+	case "ctrl+n", "down":
+		s.Find.selectNext()
+	case "ctrl+p", "up":
+		s.Find.selectPrevious()
+	default:
+		cmd := s.processKeyAnyInput(msg)
+		s.Find.filter(string(s.InputBuf))
+		return cmd
+	}
+	return nil
+}
+
+func listenFind(batches <-chan t.FindBatch) tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := <-batches
+		if !ok {
+			return nil
+		}
+		return batch
+	}
+}
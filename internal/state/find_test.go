@@ -0,0 +1,25 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+
+	tr "github.com/LeperGnome/bt/internal/tree"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindBatchesMatchFullFilter(t *testing.T) {
+	streamed := &FindState{root: "/r", query: "ab"}
+	for b := range 5 {
+		batch := []tr.FindResult{}
+		for i := range 20 {
+			batch = append(batch, tr.FindResult{Path: fmt.Sprintf("/r/a%d/x%db/%d", b, i%3, i)})
+		}
+		streamed.add(batch)
+	}
+	full := &FindState{root: "/r", results: streamed.results}
+	full.filter("ab")
+
+	require.Equal(t, 100, streamed.Total)
+	require.Equal(t, full.Matches, streamed.Matches)
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	t "github.com/LeperGnome/bt/internal/tree"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// GitStatusMsg carries git status, read in background for a tree.
+type GitStatusMsg struct {
+	tree   *t.Tree
+	dir    string
+	status *t.GitStatus
+	err    error
+}
+
+// At most one git status is read for a tree at a time, changes while reading trigger one more read.
+type gitRefresh struct {
+	dir     string // root, status was last requested for
+	running bool
+	stale   bool
+}
+
+// Starts reading git status of all local trees in background.
+func (s *State) RefreshGitStatus() tea.Cmd {
+	cmds := []tea.Cmd{}
+	for _, tree := range s.Trees {
+		cmds = append(cmds, s.refreshGitStatus(tree))
+	}
+	return tea.Batch(cmds...)
+}
+func (s *State) refreshGitStatus(tree *t.Tree) tea.Cmd {
+	if !tree.Root.IsLocal() {
+		return nil
+	}
+	g, ok := s.gitRefreshes[tree]
+	if !ok {
+		g = &gitRefresh{}
+		s.gitRefreshes[tree] = g
+	}
+	g.dir = tree.Root.Path
+	if g.running {
+		g.stale = true
+		return nil
+	}
+	g.running = true
+	dir := g.dir
+	return func() tea.Msg {
+		status, err := t.ReadGitStatus(dir)
+		return GitStatusMsg{tree: tree, dir: dir, status: status, err: err}
+	}
+}
+
+// Applies read git status. Returns command to read it again, when it got stale meanwhile.
+func (s *State) ProcessGitStatus(msg GitStatusMsg) tea.Cmd {
+	g := s.gitRefreshes[msg.tree]
+	g.running = false
+	if msg.err != nil {
+		s.ErrBuf = msg.err.Error()
+	} else {
+		msg.tree.Git = msg.status
+	}
+	if g.stale || g.dir != msg.dir {
+		g.stale = false
+		return s.refreshGitStatus(msg.tree)
+	}
+	return nil
+}
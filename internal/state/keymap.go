@@ -0,0 +1,403 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Action is a name of an operation, which can be bound to keys in config.
+type Action string
+
+const (
+	ActionSelectNext         Action = "select_next"
+	ActionSelectPrevious     Action = "select_previous"
+	ActionGoParent           Action = "go_parent"
+	ActionEnterDir           Action = "enter_dir"
+	ActionMarkToggle         Action = "mark_toggle"
+	ActionMarkTogglePrevious Action = "mark_toggle_previous"
+	ActionMove               Action = "move"
+	ActionCopy               Action = "copy"
+	ActionPaste              Action = "paste"
+	ActionTrash              Action = "trash"
+	ActionDelete             Action = "delete"
+	ActionCreateFile         Action = "create_file"
+	ActionCreateDir          Action = "create_dir"
+	ActionRename             Action = "rename"
+	ActionEdit               Action = "edit"
+	ActionUndo               Action = "undo"
+	ActionRedo               Action = "redo"
+	ActionGoTop              Action = "go_top"
+	ActionGoBottom           Action = "go_bottom"
+	ActionToggleHidden       Action = "toggle_hidden"
+	ActionToggleIgnored      Action = "toggle_ignored"
+	ActionToggleDiff         Action = "toggle_diff"
+	ActionToggleMarkdown     Action = "toggle_markdown"
+	ActionScrollPreviewDown  Action = "scroll_preview_down"
+	ActionScrollPreviewUp    Action = "scroll_preview_up"
+	ActionPreviewHalfDown    Action = "preview_half_page_down"
+	ActionPreviewHalfUp      Action = "preview_half_page_up"
+	ActionSetRoot            Action = "set_root"
+	ActionNextRoot           Action = "next_root"
+	ActionSearch             Action = "search"
+	ActionSearchNext         Action = "search_next"
+	ActionSearchPrevious     Action = "search_previous"
+	ActionFind               Action = "find"
+	ActionOpen               Action = "open"
+	ActionCancel             Action = "cancel"
+	ActionHelp               Action = "help"
+	ActionQuit               Action = "quit"
+	ActionQuitNoCd           Action = "quit_no_cd"
+	ActionAbort              Action = "abort"
+)
+
+const (
+	errNotLocalOpen   = "archive members and remote files can't be opened, copy them to a local directory first"
+	errNotLocalChoose = "archive members and remote files can't be chosen, copy them to a local directory first"
+)
+
+type actionSpec struct {
+	action Action
+	keys   []string // key sequences, keys within a sequence are separated by space
+	desc   string
+	run    func(s *State) tea.Cmd
+}
+
+// All actions with default bindings, in order they are shown in help.
+var actions = []actionSpec{
+	{ActionSelectNext, []string{"j", "down"}, "Select next child", func(s *State) tea.Cmd {
+		s.Tree.SelectNextChild()
+		return nil
+	}},
+	{ActionSelectPrevious, []string{"k", "up"}, "Select previous child", func(s *State) tea.Cmd {
+		s.Tree.SelectPreviousChild()
+		return nil
+	}},
+	{ActionGoParent, []string{"h", "left"}, "Move up a dir (goes above the root too)", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.SetParentAsCurrent())
+		return nil
+	}},
+	{ActionEnterDir, []string{"l", "right"}, "Enter selected directory or archive (.zip, .tar, .tar.gz)", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.SetSelectedChildAsCurrent())
+		return nil
+	}},
+	{ActionMarkToggle, []string{"tab"}, "Mark selected child and move down", func(s *State) tea.Cmd {
+		s.Tree.ToggleMarkSelectedChild()
+		s.Tree.SelectNextChild()
+		return nil
+	}},
+	{ActionMarkTogglePrevious, []string{"shift+tab"}, "Mark selected child and move up", func(s *State) tea.Cmd {
+		s.Tree.ToggleMarkSelectedChild()
+		s.Tree.SelectPreviousChild()
+		return nil
+	}},
+	{ActionMove, []string{"d"}, "Move marked children (then paste)", func(s *State) tea.Cmd {
+		s.startMarkedOperation(Move)
+		return nil
+	}},
+	{ActionCopy, []string{"y"}, "Copy marked children (then paste)", func(s *State) tea.Cmd {
+		s.startMarkedOperation(Copy)
+		return nil
+	}},
+	{ActionPaste, []string{"p"}, "Paste moved / copied children into current directory", func(s *State) tea.Cmd {
+		switch s.OpBuf {
+		case Move:
+			s.setErr(s.Tree.MoveMarkedToCurrentDir())
+		case Copy:
+			s.setErr(s.Tree.CopyMarkedToCurrentDir())
+		default:
+			return nil
+		}
+		s.OpBuf = Noop
+		return nil
+	}},
+	{ActionTrash, []string{"D"}, "Move marked children to trash", func(s *State) tea.Cmd {
+		s.startMarkedOperation(Delete)
+		return nil
+	}},
+	{ActionDelete, []string{"X"}, "Permanently delete marked children", func(s *State) tea.Cmd {
+		s.startMarkedOperation(PermanentDelete)
+		return nil
+	}},
+	{ActionCreateFile, []string{"i f"}, "Create file in current directory", func(s *State) tea.Cmd {
+		s.Tree.DropMark()
+		s.OpBuf = InsertFile
+		return nil
+	}},
+	{ActionCreateDir, []string{"i d"}, "Create directory in current directory", func(s *State) tea.Cmd {
+		s.Tree.DropMark()
+		s.OpBuf = InsertDir
+		return nil
+	}},
+	{ActionRename, []string{"r"}, "Rename selected child", func(s *State) tea.Cmd {
+		if len(s.Tree.Marked) == 0 {
+			if ok := s.Tree.MarkSelectedChild(); ok {
+				s.InputBuf = []rune(s.Tree.Marked[0].Info.Name())
+				s.OpBuf = Rename
+			}
+		}
+		return nil
+	}},
+	{ActionEdit, []string{"e"}, "Edit selected file in $EDITOR", func(s *State) tea.Cmd {
+		child := s.Tree.GetSelectedChild()
+		if child != nil && !child.IsLocal() {
+			s.ErrBuf = errNotLocalOpen
+			return nil
+		}
+		if child != nil && child.Info.Mode().IsRegular() {
+			return openEditor(child.Path)
+		}
+		return nil
+	}},
+	{ActionUndo, []string{"u"}, "Undo last file operation", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.Undo())
+		return nil
+	}},
+	{ActionRedo, []string{"ctrl+r"}, "Redo last undone file operation", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.Redo())
+		return nil
+	}},
+	{ActionGoTop, []string{"g g"}, "Go to top most child in current directory", func(s *State) tea.Cmd {
+		s.Tree.CurrentDir.SelectFirst()
+		return nil
+	}},
+	{ActionGoBottom, []string{"G"}, "Go to last child in current directory", func(s *State) tea.Cmd {
+		s.Tree.CurrentDir.SelectLast()
+		return nil
+	}},
+	{ActionToggleHidden, []string{"H"}, "Toggle hidden files in current directory", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.ToggleHiddenInCurrentDirectory())
+		return nil
+	}},
+	{ActionToggleIgnored, []string{"I"}, "Toggle files ignored by .gitignore / .ignore", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.ToggleIgnoreFiles())
+		return nil
+	}},
+	{ActionToggleDiff, []string{"v"}, "Toggle git diff preview of changed files", func(s *State) tea.Cmd {
+		s.DiffPreview = !s.DiffPreview
+		return nil
+	}},
+	{ActionToggleMarkdown, []string{"m"}, "Toggle rendered / raw markdown preview", func(s *State) tea.Cmd {
+		s.RawMarkdown = !s.RawMarkdown
+		return nil
+	}},
+	{ActionScrollPreviewDown, []string{"J"}, "Scroll preview down", func(s *State) tea.Cmd {
+		s.scrollPreview(1)
+		return nil
+	}},
+	{ActionScrollPreviewUp, []string{"K"}, "Scroll preview up", func(s *State) tea.Cmd {
+		s.scrollPreview(-1)
+		return nil
+	}},
+	{ActionPreviewHalfDown, []string{"ctrl+d"}, "Scroll preview down half a page", func(s *State) tea.Cmd {
+		s.scrollPreview(s.halfPage())
+		return nil
+	}},
+	{ActionPreviewHalfUp, []string{"ctrl+u"}, "Scroll preview up half a page", func(s *State) tea.Cmd {
+		s.scrollPreview(-s.halfPage())
+		return nil
+	}},
+	{ActionSetRoot, []string{"R"}, "Make current directory the root", func(s *State) tea.Cmd {
+		s.Tree.SetCurrentAsRoot()
+		return nil
+	}},
+	{ActionNextRoot, []string{"t"}, "Switch to next root (marks of move / copy follow)", func(s *State) tea.Cmd {
+		s.nextRoot()
+		return nil
+	}},
+	{ActionSearch, []string{"/"}, "Fuzzy search (enter to jump, ctrl+n / ctrl+p to cycle)", func(s *State) tea.Cmd {
+		finder := s.Tree.StartSearch(s.searchUnreadDirs)
+		s.InputBuf = []rune{}
+		s.OpBuf = Search
+		if finder != nil {
+			return listenFind(finder.Batches)
+		}
+		return nil
+	}},
+	{ActionSearchNext, []string{"n"}, "Jump to next search match", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.SearchNext())
+		return nil
+	}},
+	{ActionSearchPrevious, []string{"N"}, "Jump to previous search match", func(s *State) tea.Cmd {
+		s.setErr(s.Tree.SearchPrevious())
+		return nil
+	}},
+	{ActionFind, []string{"f"}, "Find files recursively (enter to reveal in tree)", func(s *State) tea.Cmd {
+		return s.startFind()
+	}},
+	{ActionOpen, []string{"enter"}, "Open / close selected directory or open file (xdg-open / open)", func(s *State) tea.Cmd {
+		child := s.Tree.GetSelectedChild()
+		if s.ChooseMode && child != nil && !child.Info.IsDir() {
+			if !s.choose() {
+				return nil
+			}
+			return tea.Quit
+		}
+		if child != nil && child.Info.Mode().IsRegular() {
+			if !child.IsLocal() {
+				s.ErrBuf = errNotLocalOpen
+				return nil
+			}
+			return xdgOpenFile(child.Path)
+		}
+		s.setErr(s.Tree.CollapseOrExpandSelected())
+		return nil
+	}},
+	{ActionCancel, []string{"esc"}, "Clear error message / stop current operation / drop marks", func(s *State) tea.Cmd {
+		s.Tree.DropMark()
+		s.Tree.StopSearch()
+		s.OpBuf = Noop
+		s.ErrBuf = ""
+		return nil
+	}},
+	{ActionHelp, []string{"?"}, "Toggle help", func(s *State) tea.Cmd {
+		s.HelpToggle = !s.HelpToggle
+		return nil
+	}},
+	{ActionQuit, []string{"q"}, "Exit", func(s *State) tea.Cmd {
+		if s.ChooseMode && len(s.Tree.Marked) > 0 && !s.choose() {
+			return nil
+		}
+		return tea.Quit
+	}},
+	{ActionQuitNoCd, []string{"Q"}, "Exit without changing shell directory (see --init)", func(s *State) tea.Cmd {
+		s.SkipLastDir = true
+		return tea.Quit
+	}},
+	{ActionAbort, []string{"ctrl+c"}, "Exit without printing chosen paths (see --choose)", func(s *State) tea.Cmd {
+		return tea.Quit
+	}},
+}
+
+// Keymap resolves key sequences to actions.
+type Keymap struct {
+	bindings map[string]Action
+	prefixes map[string][]string // incomplete sequence -> full sequences, starting with it
+	keys     map[Action][]string
+}
+
+// Builds keymap from default bindings, replacing keys of actions, present in overrides.
+func NewKeymap(overrides map[string][]string) (Keymap, error) {
+	km := Keymap{
+		bindings: map[string]Action{},
+		prefixes: map[string][]string{},
+		keys:     map[Action][]string{},
+	}
+	for name := range overrides {
+		if !slices.ContainsFunc(actions, func(a actionSpec) bool { return string(a.action) == name }) {
+			return Keymap{}, fmt.Errorf("unknown action '%s' in key bindings", name)
+		}
+	}
+	// Overridden keys take precedence over defaults of other actions.
+	taken := map[string]bool{}
+	for _, keys := range overrides {
+		for _, k := range keys {
+			taken[normalizeSeq(k)] = true
+		}
+	}
+	for _, a := range actions {
+		keys, overridden := overrides[string(a.action)]
+		if !overridden {
+			keys = slices.DeleteFunc(slices.Clone(a.keys), func(k string) bool { return taken[k] })
+		}
+		for _, k := range keys {
+			seq := normalizeSeq(k)
+			if seq == "" {
+				return Keymap{}, fmt.Errorf("empty key for action '%s'", a.action)
+			}
+			if other, ok := km.bindings[seq]; ok && other != a.action {
+				return Keymap{}, fmt.Errorf("key '%s' is bound to both '%s' and '%s'", seq, other, a.action)
+			}
+			km.bindings[seq] = a.action
+			km.keys[a.action] = append(km.keys[a.action], seq)
+
+			parts := strings.Split(seq, " ")
+			for i := 1; i < len(parts); i++ {
+				prefix := strings.Join(parts[:i], " ")
+				km.prefixes[prefix] = append(km.prefixes[prefix], seq)
+			}
+		}
+	}
+	for prefix := range km.prefixes {
+		if action, ok := km.bindings[prefix]; ok {
+			return Keymap{}, fmt.Errorf("key '%s' of '%s' is a prefix of other bindings", prefix, action)
+		}
+	}
+	return km, nil
+}
+
+type HelpLine struct {
+	Keys   string
+	Action Action
+	Desc   string
+}
+
+// Returns help for every bound action.
+func (km Keymap) Help() []HelpLine {
+	lines := []HelpLine{}
+	for _, a := range actions {
+		keys := km.keys[a.action]
+		if len(keys) == 0 {
+			continue
+		}
+		shown := []string{}
+		for _, k := range keys {
+			shown = append(shown, displaySeq(k))
+		}
+		lines = append(lines, HelpLine{Keys: strings.Join(shown, " / "), Action: a.action, Desc: a.desc})
+	}
+	return lines
+}
+
+// Returns possible continuations of an incomplete key sequence.
+func (km Keymap) Continuations(prefix string) []HelpLine {
+	lines := []HelpLine{}
+	for _, seq := range km.prefixes[prefix] {
+		rest := strings.TrimPrefix(seq, prefix+" ")
+		action := km.bindings[seq]
+		lines = append(lines, HelpLine{Keys: displaySeq(rest), Action: action, Desc: string(action)})
+	}
+	return lines
+}
+
+func runAction(s *State, action Action) tea.Cmd {
+	idx := slices.IndexFunc(actions, func(a actionSpec) bool { return a.action == action })
+	return actions[idx].run(s)
+}
+
+// Collapses repeated spaces, so "g  g" and "g g" are the same sequence.
+func normalizeSeq(seq string) string {
+	return strings.Join(strings.Fields(seq), " ")
+}
+
+// Shows sequence of single character keys without spaces, e.g. "g g" as "gg".
+func displaySeq(seq string) string {
+	parts := strings.Split(seq, " ")
+	for _, p := range parts {
+		if len([]rune(p)) != 1 {
+			return seq
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Renders help as a markdown table, which is kept in README.
+func markdownTable(lines []HelpLine) string {
+	keysWidth, actionWidth, descWidth := len("key"), len("action"), len("desc")
+	for _, l := range lines {
+		keysWidth = max(keysWidth, len(l.Keys))
+		actionWidth = max(actionWidth, len(l.Action))
+		descWidth = max(descWidth, len(l.Desc))
+	}
+	row := func(keys, action, desc string) string {
+		return fmt.Sprintf("| %-*s | %-*s | %-*s |\n", keysWidth, keys, actionWidth, action, descWidth, desc)
+	}
+	table := row("key", "action", "desc")
+	table += row(strings.Repeat("-", keysWidth), strings.Repeat("-", actionWidth), strings.Repeat("-", descWidth))
+	for _, l := range lines {
+		table += row(l.Keys, string(l.Action), l.Desc)
+	}
+	return table
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var update = flag.Bool("update", false, "update key bindings table in README")
+
+const readmePath = "../../README.md"
+
+type KeymapTestSuite struct {
+	suite.Suite
+}
+
+func TestKeymapTestSuite(t *testing.T) {
+	suite.Run(t, new(KeymapTestSuite))
+}
+
+func (s *KeymapTestSuite) TestDefaults() {
+	km, err := NewKeymap(nil)
+	s.Require().NoError(err)
+
+	s.Require().Equal(ActionSelectNext, km.bindings["j"])
+	s.Require().Equal(ActionGoTop, km.bindings["g g"])
+	s.Require().Contains(km.prefixes, "g")
+	s.Require().Equal([]HelpLine{{Keys: "g", Action: ActionGoTop, Desc: string(ActionGoTop)}}, km.Continuations("g"))
+}
+
+func (s *KeymapTestSuite) TestOverrides() {
+	km, err := NewKeymap(map[string][]string{
+		"select_next": {"n"},
+		"go_top":      {"ctrl+g  t"},
+	})
+	s.Require().NoError(err)
+
+	s.Require().Equal(ActionSelectNext, km.bindings["n"])
+	_, ok := km.bindings["j"]
+	s.Require().False(ok, "overridden action loses default keys")
+	s.Require().Equal([]string{"N"}, km.keys[ActionSearchPrevious])
+	s.Require().NotContains(km.keys, ActionSearchNext, "default key is taken by override")
+	s.Require().Equal(ActionGoTop, km.bindings["ctrl+g t"])
+}
+
+func (s *KeymapTestSuite) TestInvalid() {
+	_, err := NewKeymap(map[string][]string{"fly": {"w"}})
+	s.Require().ErrorContains(err, "unknown action 'fly'")
+
+	_, err = NewKeymap(map[string][]string{"select_next": {"x"}, "select_previous": {"x"}})
+	s.Require().ErrorContains(err, "bound to both")
+
+	_, err = NewKeymap(map[string][]string{"go_bottom": {"g"}})
+	s.Require().ErrorContains(err, "prefix")
+}
+
+func (s *KeymapTestSuite) TestSequence() {
+	state := &State{}
+	var err error
+	state.Keymap, err = NewKeymap(map[string][]string{"help": {"z z"}})
+	s.Require().NoError(err)
+
+	key := func(r rune) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}} }
+
+	state.processKeyDefault(key('z'))
+	s.Require().Equal("z", state.PendingKeys())
+	state.processKeyDefault(key('z'))
+	s.Require().Empty(state.PendingKeys())
+	s.Require().True(state.HelpToggle)
+
+	// Broken sequence starts over from the last key.
+	state.processKeyDefault(key('z'))
+	state.processKeyDefault(key('?'))
+	state.processKeyDefault(key('z'))
+	s.Require().Equal("z", state.PendingKeys())
+	s.Require().True(state.HelpToggle)
+}
+
+func TestReadmeKeyTable(t *testing.T) {
+	km, err := NewKeymap(nil)
+	require.NoError(t, err)
+	table := markdownTable(km.Help())
+
+	raw, err := os.ReadFile(readmePath)
+	require.NoError(t, err)
+	readme := string(raw)
+
+	const header = "Key bindings:\n\n"
+	start := strings.Index(readme, header)
+	require.GreaterOrEqual(t, start, 0, "key bindings section not found")
+	start += len(header)
+	end := start + strings.Index(readme[start:], "\n\n") + 1
+
+	if *update {
+		readme = readme[:start] + table + readme[end:]
+		require.NoError(t, os.WriteFile(readmePath, []byte(readme), 0644))
+		return
+	}
+	require.Equal(t, table, readme[start:end], "README is out of date, run: go test ./internal/state -run TestReadmeKeyTable -update")
+}
@@ -0,0 +1,33 @@
+package state
+
+// Scrolls the preview by delta lines. Offset is clamped to the end of the file,
+// when the preview is generated, see ClampPreviewOffset.
+func (s *State) scrollPreview(delta int) {
+	s.syncPreviewOffset()
+	s.PreviewOffset = max(s.PreviewOffset+delta, 0)
+}
+
+// Half of the window is scrolled by ctrl+d / ctrl+u, like in vim.
+func (s *State) halfPage() int {
+	return max(s.WindowHeight/2, 1)
+}
+
+// Limits offset by the greatest one, which still fills the preview of the file at path.
+// Negative maxOffset means it's unknown.
+func (s *State) ClampPreviewOffset(path string, maxOffset int) {
+	if path == s.previewPath && maxOffset >= 0 {
+		s.PreviewOffset = min(s.PreviewOffset, maxOffset)
+	}
+}
+
+// Offset is reset, when another child gets selected.
+func (s *State) syncPreviewOffset() {
+	path := ""
+	if ch := s.Tree.GetSelectedChild(); ch != nil {
+		path = ch.Path
+	}
+	if path != s.previewPath {
+		s.previewPath = path
+		s.PreviewOffset = 0
+	}
+}
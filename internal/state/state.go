@@ -1,10 +1,16 @@
 package state
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	runtime "runtime"
+	"slices"
+	"strings"
 
+	"github.com/LeperGnome/bt/internal/config"
 	t "github.com/LeperGnome/bt/internal/tree"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -16,11 +22,12 @@ const (
 	Move
 	Copy
 	Delete
-	Go
-	Insert
+	PermanentDelete
 	InsertFile
 	InsertDir
 	Rename
+	Search
+	Find
 )
 
 func (o Operation) Repr() string {
@@ -28,17 +35,18 @@ func (o Operation) Repr() string {
 		"",
 		"moving",
 		"copying",
-		"confirm removing (y/n) of",
-		"g",
-		"create new (f)ile/(d)irectory",
+		"confirm moving to trash (y/n) of",
+		"confirm PERMANENTLY removing (y/n) of",
 		"enter new file name:",
 		"enter new directory name:",
 		"renaming",
+		"search:",
+		"find:",
 	}[o]
 }
 func (o Operation) IsInput() bool {
 	switch o {
-	case InsertDir, InsertFile, Rename:
+	case InsertDir, InsertFile, Rename, Search, Find:
 		return true
 	default:
 		return false
@@ -46,57 +54,169 @@ func (o Operation) IsInput() bool {
 }
 
 type State struct {
-	Tree        *t.Tree
+	Tree        *t.Tree   // current root
+	Trees       []*t.Tree // all roots, bt was started with
 	OpBuf       Operation
-	PrevOpBuf   Operation
 	InputBuf    []rune
 	ErrBuf      string
-	NodeChanges <-chan t.NodeChange
+	NodeChanges <-chan NodeChange
 	HelpToggle  bool
+	DiffPreview bool       // preview changed files in git repository as a diff
+	RawMarkdown bool       // preview markdown source instead of rendered one
+	Find        *FindState // nil, when not finding
+	ChooseMode  bool       // enter on a file or quitting with marks chooses paths
+	Chosen      []string
+	SkipLastDir bool // quit without changing shell's directory
+	Keymap      Keymap
+
+	PreviewOffset int // lines, preview of the selected child is scrolled down by
+	WindowHeight  int // for scrolling by half a page
+
+	pendingKeys      []string
+	previewPath      string // selected child, which PreviewOffset applies to
+	gitRefreshes     map[*t.Tree]*gitRefresh
+	searchUnreadDirs bool
+	findDepthLimit   int
 }
 
-func InitState(root string) (*State, error) {
-	tree, ncc, err := t.InitTree(root, nil)
+// Roots are local directories or sftp:// URLs.
+func InitState(roots []string, conf config.BtConfig) (*State, error) {
+	keymap, err := NewKeymap(conf.Keys)
 	if err != nil {
 		return nil, err
 	}
+	trees := []*t.Tree{}
+	changes := []<-chan t.NodeChange{}
+	// Connections of already opened roots are closed on error.
+	fail := func(err error) (*State, error) {
+		for _, tree := range trees {
+			tree.Close()
+		}
+		return nil, err
+	}
+	for _, root := range roots {
+		fsys, dir, err := t.OpenFS(root)
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", root, err))
+		}
+		tree, ncc, err := t.InitTree(fsys, dir, nil)
+		if err != nil {
+			if c, ok := fsys.(io.Closer); ok {
+				c.Close()
+			}
+			return fail(err)
+		}
+		trees = append(trees, tree)
+		changes = append(changes, ncc)
+		if conf.RespectGitignore {
+			if err := tree.ToggleIgnoreFiles(); err != nil {
+				return fail(err)
+			}
+		}
+	}
 	return &State{
-		Tree:        tree,
+		Tree:        trees[0],
+		Trees:       trees,
 		OpBuf:       Noop,
 		InputBuf:    []rune{},
-		NodeChanges: ncc,
+		NodeChanges: mergeNodeChanges(trees, changes),
+		Keymap:      keymap,
+
+		gitRefreshes:     map[*t.Tree]*gitRefresh{},
+		searchUnreadDirs: conf.SearchUnreadDirs,
+		findDepthLimit:   conf.FindDepthLimit,
 	}, nil
 }
 
-func (s *State) ProcessNodeChange(nodeChange t.NodeChange) tea.Cmd {
-	err := s.Tree.RefreshNodeParentByPath(nodeChange.Path)
+// Closes all roots, including connections to remote ones.
+func (s *State) Close() error {
+	errs := []error{}
+	for _, tree := range s.Trees {
+		errs = append(errs, tree.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Change is applied to the root it happened in. Paths of different roots
+// may coincide, e.g. of a local and a remote one.
+func (s *State) ProcessNodeChange(nodeChange NodeChange) tea.Cmd {
+	tree := nodeChange.tree
+	tree.ObserveChange(nodeChange.Path)
+	err := tree.RefreshNodeParentByPath(nodeChange.Path)
 	if err != nil {
 		s.ErrBuf = err.Error()
 	}
-	s.Tree.RemoveNodeFromMarkByPath(nodeChange.Path)
-	return nil
+	// Marks of a move or copy could be taken to another root.
+	for _, other := range s.Trees {
+		other.RemoveNodeFromMarkByPath(tree, nodeChange.Path)
+	}
+	s.syncPreviewOffset()
+	return s.RefreshGitStatus()
+}
+
+// Index of the current root among all of them.
+func (s *State) RootIdx() int {
+	return slices.Index(s.Trees, s.Tree)
+}
+
+// Switches to the next root. Marks of a started move or copy are taken along,
+// so they can be pasted there.
+func (s *State) nextRoot() {
+	if len(s.Trees) < 2 {
+		return
+	}
+	next := s.Trees[(s.RootIdx()+1)%len(s.Trees)]
+	if s.OpBuf == Move || s.OpBuf == Copy {
+		next.Marked, s.Tree.Marked = append(next.Marked, s.Tree.Marked...), nil
+	}
+	s.Tree = next
+}
+
+// NodeChange is a file system change, tagged with a root it happened in.
+type NodeChange struct {
+	t.NodeChange
+	tree *t.Tree
+}
+
+func mergeNodeChanges(trees []*t.Tree, chans []<-chan t.NodeChange) <-chan NodeChange {
+	merged := make(chan NodeChange)
+	for i, ch := range chans {
+		go func() {
+			for c := range ch {
+				merged <- NodeChange{NodeChange: c, tree: trees[i]}
+			}
+		}()
+	}
+	return merged
 }
 
 func (s *State) ProcessKey(msg tea.KeyMsg) tea.Cmd {
+	cmd := s.processKey(msg)
+	s.syncPreviewOffset()
+	// Root could move to another repository.
+	if g := s.gitRefreshes[s.Tree]; g != nil && g.dir != s.Tree.Root.Path {
+		return tea.Batch(cmd, s.refreshGitStatus(s.Tree))
+	}
+	return cmd
+}
+func (s *State) processKey(msg tea.KeyMsg) tea.Cmd {
 	switch s.OpBuf {
 	case Noop:
 		return s.processKeyDefault(msg)
-	case Move:
-		return s.processKeyMove(msg)
 	case Delete:
 		return s.processKeyDelete(msg)
-	case Copy:
-		return s.processKeyCopy(msg)
-	case Go:
-		return s.processKeyGo(msg)
-	case Insert:
-		return s.processKeyInsert(msg)
+	case PermanentDelete:
+		return s.processKeyPermanentDelete(msg)
 	case InsertFile:
 		return s.processKeyInsertFile(msg)
 	case InsertDir:
 		return s.processKeyInsertDir(msg)
 	case Rename:
 		return s.processKeyRename(msg)
+	case Search:
+		return s.processKeySearch(msg)
+	case Find:
+		return s.processKeyFind(msg)
 	default:
 		return s.processKeyDefault(msg)
 	}
@@ -116,15 +236,33 @@ func (s *State) processKeyRename(msg tea.KeyMsg) tea.Cmd {
 	}
 	return nil
 }
-func (s *State) processKeyInsert(msg tea.KeyMsg) tea.Cmd {
+func (s *State) processKeySearch(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
-	case "f":
-		s.OpBuf = InsertFile
-	case "d":
-		s.OpBuf = InsertDir
-	default:
+	case "enter":
+		if err := s.Tree.RevealCurrentMatch(); err != nil {
+			s.ErrBuf = err.Error()
+		}
 		s.OpBuf = Noop
-		return s.processKeyDefault(msg)
+		s.InputBuf = []rune{}
+	case "ctrl+c", "esc":
+		s.Tree.StopSearch()
+		return s.processKeyAnyInput(msg)
+	case "ctrl+n", "down":
+		if err := s.Tree.SearchNext(); err != nil {
+			s.ErrBuf = err.Error()
+		}
+	case "ctrl+p", "up":
+		if err := s.Tree.SearchPrevious(); err != nil {
+			s.ErrBuf = err.Error()
+		}
+	default:
+		cmd := s.processKeyAnyInput(msg)
+		s.Tree.UpdateSearch(string(s.InputBuf))
+		// Following the best match while typing, but only if it's already visible.
+		if m, ok := s.Tree.Search.CurrentMatch(); ok && m.Node != nil {
+			s.Tree.RevealCurrentMatch()
+		}
+		return cmd
 	}
 	return nil
 }
@@ -160,7 +298,6 @@ func (s *State) processKeyAnyInput(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "ctrl+c", "esc":
 		s.OpBuf = Noop
-		s.PrevOpBuf = Noop
 		s.InputBuf = []rune{}
 		s.Tree.DropMark()
 	case "backspace":
@@ -172,16 +309,6 @@ func (s *State) processKeyAnyInput(msg tea.KeyMsg) tea.Cmd {
 	}
 	return nil
 }
-func (s *State) processKeyGo(msg tea.KeyMsg) tea.Cmd {
-	s.OpBuf = s.PrevOpBuf
-	switch msg.String() {
-	case "g":
-		s.Tree.CurrentDir.SelectFirst()
-	default:
-		return s.processKeyDefault(msg)
-	}
-	return nil
-}
 func (s *State) processKeyDelete(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
 	case "y":
@@ -197,113 +324,76 @@ func (s *State) processKeyDelete(msg tea.KeyMsg) tea.Cmd {
 	}
 	return nil
 }
-func (s *State) processKeyMove(msg tea.KeyMsg) tea.Cmd {
+func (s *State) processKeyPermanentDelete(msg tea.KeyMsg) tea.Cmd {
 	switch msg.String() {
-	case "p":
-		err := s.Tree.MoveMarkedToCurrentDir()
+	case "y":
+		err := s.Tree.DeleteMarkedPermanently()
 		if err != nil {
 			s.ErrBuf = err.Error()
 		}
 		s.OpBuf = Noop
 	default:
+		s.OpBuf = Noop
+		s.Tree.DropMark()
 		return s.processKeyDefault(msg)
 	}
 	return nil
 }
-func (s *State) processKeyCopy(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "p":
-		err := s.Tree.CopyMarkedToCurrentDir()
-		if err != nil {
-			s.ErrBuf = err.Error()
-		}
-		s.OpBuf = Noop
-	default:
+func (s *State) processKeyDefault(msg tea.KeyMsg) tea.Cmd {
+	s.pendingKeys = append(s.pendingKeys, msg.String())
+	seq := strings.Join(s.pendingKeys, " ")
+	if action, ok := s.Keymap.bindings[seq]; ok {
+		s.pendingKeys = nil
+		return runAction(s, action)
+	}
+	if _, ok := s.Keymap.prefixes[seq]; ok {
+		return nil
+	}
+	// Unknown sequence, starting over from the last key.
+	if len(s.pendingKeys) > 1 {
+		s.pendingKeys = nil
 		return s.processKeyDefault(msg)
 	}
+	s.pendingKeys = nil
 	return nil
 }
-func (s *State) processKeyDefault(msg tea.KeyMsg) tea.Cmd {
-	switch msg.String() {
-	case "esc":
-		s.Tree.DropMark()
-		s.OpBuf = Noop
-		s.ErrBuf = ""
-	case "ctrl+c", "q":
-		return tea.Quit
-	case "shift+tab":
-		s.Tree.ToggleMarkSelectedChild()
-		s.Tree.SelectPreviousChild()
-	case "tab":
-		s.Tree.ToggleMarkSelectedChild()
-		s.Tree.SelectNextChild()
-	case "j", "down":
-		s.Tree.SelectNextChild()
-	case "k", "up":
-		s.Tree.SelectPreviousChild()
-	case "l", "right":
-		err := s.Tree.SetSelectedChildAsCurrent()
-		if err != nil {
-			s.ErrBuf = err.Error()
-		}
-	case "h", "left":
-		s.Tree.SetParentAsCurrent()
-	case "y":
-		if len(s.Tree.Marked) != 0 {
-			s.OpBuf = Copy
-		} else if ok := s.Tree.MarkSelectedChild(); ok {
-			s.OpBuf = Copy
-		}
-	case "d":
-		if len(s.Tree.Marked) != 0 {
-			s.OpBuf = Move
-		} else if ok := s.Tree.MarkSelectedChild(); ok {
-			s.OpBuf = Move
-		}
-	case "D":
-		if len(s.Tree.Marked) != 0 {
-			s.OpBuf = Delete
-		} else if ok := s.Tree.MarkSelectedChild(); ok {
-			s.OpBuf = Delete
-		}
-	case "g":
-		s.PrevOpBuf = s.OpBuf
-		s.OpBuf = Go
-	case "G":
-		s.Tree.CurrentDir.SelectLast()
-	case "i":
-		s.Tree.DropMark()
-		s.OpBuf = Insert
-	case "r":
-		if len(s.Tree.Marked) == 0 {
-			if ok := s.Tree.MarkSelectedChild(); ok {
-				s.InputBuf = []rune(s.Tree.Marked[0].Info.Name())
-				s.OpBuf = Rename
-			}
-		}
-	case "e":
-		child := s.Tree.GetSelectedChild()
-		if child != nil && child.Info.Mode().IsRegular() {
-			return openEditor(child.Path)
-		}
-	case "H":
-		if err := s.Tree.ToggleHiddenInCurrentDirectory(); err != nil {
-			s.ErrBuf = err.Error()
-		}
-	case "?":
-		s.HelpToggle = !s.HelpToggle
-	case "enter":
-		child := s.Tree.GetSelectedChild()
-		if child != nil && child.Info.Mode().IsRegular() {
-			return xdgOpenFile(child.Path)
-		} else {
-			err := s.Tree.CollapseOrExpandSelected()
-			if err != nil {
-				s.ErrBuf = err.Error()
-			}
+
+// Keys of the incomplete sequence, e.g. "g" while waiting for "gg".
+func (s *State) PendingKeys() string {
+	return strings.Join(s.pendingKeys, " ")
+}
+
+// Marks selected child, if nothing is marked, and starts operation over marked children.
+func (s *State) startMarkedOperation(op Operation) {
+	if len(s.Tree.Marked) != 0 {
+		s.OpBuf = op
+	} else if ok := s.Tree.MarkSelectedChild(); ok {
+		s.OpBuf = op
+	}
+}
+func (s *State) setErr(err error) {
+	if err != nil {
+		s.ErrBuf = err.Error()
+	}
+}
+
+// Chooses marked nodes or selected one, if nothing is marked.
+// Only local files can be chosen, as the shell can't open others.
+func (s *State) choose() bool {
+	nodes := s.Tree.Marked
+	if selected := s.Tree.GetSelectedChild(); len(nodes) == 0 && selected != nil {
+		nodes = []*t.Node{selected}
+	}
+	s.Chosen = nil
+	for _, node := range nodes {
+		if !node.IsLocal() {
+			s.Chosen = nil
+			s.ErrBuf = errNotLocalChoose
+			return false
 		}
+		s.Chosen = append(s.Chosen, node.Path)
 	}
-	return nil
+	return true
 }
 
 func openEditor(path string) tea.Cmd {
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	tr "github.com/LeperGnome/bt/internal/tree"
+	"github.com/stretchr/testify/require"
+)
+
+// Roots with coinciding paths on different file systems.
+func initSameLayoutTrees(t *testing.T) []*tr.Tree {
+	trees := []*tr.Tree{}
+	for range 2 {
+		fsys := tr.NewMemFS()
+		require.NoError(t, fsys.Mkdir("/r", os.ModePerm))
+		f, err := fsys.OpenFile("/r/file.txt", os.O_WRONLY|os.O_CREATE, 0o644)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		tree, _, err := tr.InitTree(fsys, "/r", nil)
+		require.NoError(t, err)
+		trees = append(trees, tree)
+	}
+	return trees
+}
+
+func TestNodeChangeAppliesToItsRoot(t *testing.T) {
+	trees := initSameLayoutTrees(t)
+	s := &State{Tree: trees[0], Trees: trees, gitRefreshes: map[*tr.Tree]*gitRefresh{}}
+	for _, tree := range trees {
+		require.True(t, tree.MarkSelectedChild())
+	}
+
+	s.ProcessNodeChange(NodeChange{NodeChange: tr.NodeChange{Path: "/r/file.txt"}, tree: trees[0]})
+
+	require.Empty(t, trees[0].Marked)
+	require.Len(t, trees[1].Marked, 1)
+}
+
+func TestNextRootKeepsTargetMarks(t *testing.T) {
+	trees := initSameLayoutTrees(t)
+	s := &State{Tree: trees[0], Trees: trees, OpBuf: Copy}
+	for _, tree := range trees {
+		require.True(t, tree.MarkSelectedChild())
+	}
+
+	s.nextRoot()
+
+	require.Equal(t, trees[1], s.Tree)
+	require.Len(t, trees[1].Marked, 2)
+	require.Empty(t, trees[0].Marked)
+}
+
+func TestChooseRejectsNotLocal(t *testing.T) {
+	trees := initSameLayoutTrees(t)
+	s := &State{Tree: trees[0], Trees: trees, ChooseMode: true}
+
+	require.False(t, s.choose())
+	require.Nil(t, s.Chosen)
+	require.Equal(t, errNotLocalChoose, s.ErrBuf)
+}
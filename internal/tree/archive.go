@@ -0,0 +1,321 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errReadOnlyArchive = errors.New("archive members are read-only, copy them out instead")
+
+// Checks if file can be browsed as a directory.
+func IsArchiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive is an index of archive members, read once when archive is opened.
+type Archive struct {
+	fsys     FS
+	path     string
+	children map[string][]fs.FileInfo
+	members  map[string]fs.FileInfo
+
+	// Zip archives are read at random, so the file is kept open while archive is browsed.
+	// It's released, when current directory leaves the archive, and reopened on demand.
+	isZip   bool
+	mu      sync.Mutex
+	file    File
+	zip     *zip.Reader
+	readers int  // open member readers, which use the file
+	release bool // file is closed, when the last reader is closed
+}
+
+func OpenArchive(fsys FS, archivePath string) (*Archive, error) {
+	a := &Archive{
+		fsys:     fsys,
+		path:     archivePath,
+		children: map[string][]fs.FileInfo{},
+		members:  map[string]fs.FileInfo{},
+		isZip:    strings.HasSuffix(strings.ToLower(archivePath), ".zip"),
+	}
+	if a.isZip {
+		zr, err := a.zipReader()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			a.add(f.Name, f.FileInfo())
+		}
+		return a, nil
+	}
+	f, err := fsys.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	tr, closer, err := tarReader(f, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return a, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		a.add(hdr.Name, hdr.FileInfo())
+	}
+}
+
+// Releases archive file. Members, that are being read, keep it open until they are closed.
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.readers > 0 {
+		a.release = true
+		return nil
+	}
+	return a.closeFile()
+}
+
+// Should be called with mu held.
+func (a *Archive) closeFile() error {
+	a.release = false
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file, a.zip = nil, nil
+	return err
+}
+
+// Returns zip reader, opening archive file, if it was released.
+// Should be called with mu held, except for the first call in OpenArchive.
+func (a *Archive) zipReader() (*zip.Reader, error) {
+	a.release = false
+	if a.zip != nil {
+		return a.zip, nil
+	}
+	f, err := a.fsys.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a.file, a.zip = f, zr
+	return zr, nil
+}
+
+func (a *Archive) openZipMember(member string) (io.ReadCloser, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	zr, err := a.zipReader()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if name, ok := cleanMemberName(f.Name); ok && name == member {
+			r, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			a.readers++
+			closed := false
+			return struct {
+				io.Reader
+				io.Closer
+			}{r, closerFunc(func() error {
+				a.mu.Lock()
+				defer a.mu.Unlock()
+				if closed {
+					return nil
+				}
+				closed = true
+				r.Close()
+				if a.readers--; a.readers == 0 && a.release {
+					return a.closeFile()
+				}
+				return nil
+			})}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: filepath.Join(a.path, member), Err: fs.ErrNotExist}
+}
+
+// Adds member with all of it's parent directories.
+func (a *Archive) add(name string, info fs.FileInfo) {
+	name, ok := cleanMemberName(name)
+	if !ok {
+		return
+	}
+	if _, exists := a.members[name]; exists {
+		return
+	}
+	parent := path.Dir(name)
+	if parent == "." {
+		parent = ""
+	} else if _, exists := a.members[parent]; !exists {
+		a.add(parent, archiveDirInfo{name: path.Base(parent)})
+	}
+	a.members[name] = info
+	a.children[parent] = append(a.children[parent], info)
+}
+
+// Lists member directory, "" is the archive root.
+func (a *Archive) readDir(member string) []fs.FileInfo {
+	return a.children[member]
+}
+
+// Opens member for reading.
+func (a *Archive) open(member string) (io.ReadCloser, error) {
+	if a.isZip {
+		return a.openZipMember(member)
+	}
+	// Tar can be read only sequentially, so it's scanned until the member.
+	f, err := a.fsys.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	tr, closer, err := tarReader(f, a.path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			closer.Close()
+			if err == io.EOF {
+				err = &fs.PathError{Op: "open", Path: filepath.Join(a.path, member), Err: fs.ErrNotExist}
+			}
+			return nil, err
+		}
+		if name, ok := cleanMemberName(hdr.Name); ok && name == member {
+			return struct {
+				io.Reader
+				io.Closer
+			}{tr, closer}, nil
+		}
+	}
+}
+
+// Returned closer closes decompressor together with f. On error f is closed.
+func tarReader(f io.ReadCloser, name string) (*tar.Reader, io.Closer, error) {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), closerFunc(func() error {
+			return errors.Join(gz.Close(), f.Close())
+		}), nil
+	}
+	return tar.NewReader(f), f, nil
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error { return c() }
+
+// Normalizes member name, rejecting ones, that point outside of the archive.
+func cleanMemberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
+
+// Info of a directory, which has no own entry in archive.
+type archiveDirInfo struct {
+	name string
+}
+
+func (i archiveDirInfo) Name() string       { return i.name }
+func (i archiveDirInfo) Size() int64        { return 0 }
+func (i archiveDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0o755 }
+func (i archiveDirInfo) ModTime() time.Time { return time.Time{} }
+func (i archiveDirInfo) IsDir() bool        { return true }
+func (i archiveDirInfo) Sys() any           { return nil }
+
+// Info of an opened archive file, which is shown as a directory.
+type archiveRootInfo struct {
+	fs.FileInfo
+}
+
+func (i archiveRootInfo) Mode() fs.FileMode { return i.FileInfo.Mode() | fs.ModeDir }
+func (i archiveRootInfo) IsDir() bool       { return true }
+
+// Writes archive member (recursively for directories) to dst.
+func extractNode(fsys FS, n *Node, dst string) error {
+	if _, err := fsys.Lstat(dst); err == nil {
+		return &FileOpError{Op: "extract", Path: dst, Err: fs.ErrExist}
+	}
+	if n.Info.IsDir() {
+		if err := fsys.Mkdir(dst, n.Info.Mode().Perm()|0o700); err != nil {
+			return &FileOpError{Op: "extract", Path: dst, Err: unwrapPathErr(err)}
+		}
+		for _, info := range n.archive.readDir(n.archiveMember) {
+			child := &Node{
+				Path:          filepath.Join(n.Path, info.Name()),
+				Info:          info,
+				archive:       n.archive,
+				archiveMember: path.Join(n.archiveMember, info.Name()),
+			}
+			if err := extractNode(fsys, child, filepath.Join(dst, info.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !n.Info.Mode().IsRegular() {
+		return &FileOpError{Op: "extract", Path: n.Path, Err: fmt.Errorf("only regular files and directories can be extracted")}
+	}
+	src, err := n.Open()
+	if err != nil {
+		return &FileOpError{Op: "extract", Path: n.Path, Err: unwrapPathErr(err)}
+	}
+	defer src.Close()
+	out, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, n.Info.Mode().Perm()|0o200)
+	if err != nil {
+		return &FileOpError{Op: "extract", Path: dst, Err: unwrapPathErr(err)}
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return &FileOpError{Op: "extract", Path: dst, Err: err}
+	}
+	if err := out.Close(); err != nil {
+		return &FileOpError{Op: "extract", Path: dst, Err: unwrapPathErr(err)}
+	}
+	if mt := n.Info.ModTime(); !mt.IsZero() {
+		fsys.Chtimes(dst, mt, mt)
+	}
+	return nil
+}
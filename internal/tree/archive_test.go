@@ -0,0 +1,194 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ArchiveTestSuite struct {
+	suite.Suite
+	fsys FS
+	dir  string
+	tree *Tree
+}
+
+var archiveMembers = map[string]string{
+	"readme.txt":        "hello",
+	"src/main.go":       "package main",
+	"src/pkg/util.go":   "package pkg",
+	"./docs/guide.md":   "# guide",
+	"../escape.txt":     "must be skipped",
+	"/absolute/abs.txt": "kept inside",
+}
+
+func (s *ArchiveTestSuite) SetupTest() {
+	s.T().Setenv("XDG_DATA_HOME", "/data")
+	s.fsys = NewMemFS()
+	s.dir = "/archives"
+	s.Require().NoError(s.fsys.Mkdir(s.dir, 0o755))
+
+	s.writeZip(filepath.Join(s.dir, "a.zip"))
+	s.writeTar(filepath.Join(s.dir, "b.tar.gz"))
+
+	tree, _, err := InitTree(s.fsys, s.dir, defaultNodeSorting)
+	s.Require().NoError(err)
+	s.tree = tree
+}
+
+func (s *ArchiveTestSuite) create(path string) File {
+	f, err := s.fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	s.Require().NoError(err)
+	return f
+}
+
+func (s *ArchiveTestSuite) writeZip(path string) {
+	f := s.create(path)
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range archiveMembers {
+		w, err := zw.Create(name)
+		s.Require().NoError(err)
+		_, err = io.WriteString(w, content)
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(zw.Close())
+}
+
+func (s *ArchiveTestSuite) writeTar(path string) {
+	f := s.create(path)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	s.Require().NoError(tw.WriteHeader(&tar.Header{Name: "src/", Typeflag: tar.TypeDir, Mode: 0o755}))
+	for name, content := range archiveMembers {
+		s.Require().NoError(tw.WriteHeader(&tar.Header{Name: name, Mode: 0o640, Size: int64(len(content))}))
+		_, err := io.WriteString(tw, content)
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(tw.Close())
+	s.Require().NoError(gz.Close())
+}
+
+func (s *ArchiveTestSuite) selectChild(name string) {
+	idx := slices.IndexFunc(s.tree.CurrentDir.Children, func(n *Node) bool { return n.Info.Name() == name })
+	s.Require().GreaterOrEqual(idx, 0, name)
+	s.tree.CurrentDir.selectedChildIdx = idx
+}
+
+func (s *ArchiveTestSuite) childNames(n *Node) []string {
+	names := []string{}
+	for _, ch := range n.Children {
+		names = append(names, ch.Info.Name())
+	}
+	return names
+}
+
+func (s *ArchiveTestSuite) TestBrowse() {
+	for _, archive := range []string{"a.zip", "b.tar.gz"} {
+		s.selectChild(archive)
+		s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+		s.Require().Equal(archive, s.tree.CurrentDir.Info.Name())
+		s.Require().True(s.tree.CurrentDir.Info.IsDir())
+		s.Require().Equal([]string{"absolute", "docs", "src", "readme.txt"}, s.childNames(s.tree.CurrentDir))
+
+		s.selectChild("src")
+		s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+		s.Require().Equal([]string{"pkg", "main.go"}, s.childNames(s.tree.CurrentDir))
+
+		s.selectChild("main.go")
+		main := s.tree.GetSelectedChild()
+		s.Require().True(main.IsVirtual())
+		s.Require().Equal(filepath.Join(s.dir, archive, "src", "main.go"), main.Path)
+		buf := make([]byte, 100)
+		n, err := main.ReadContent(buf, 0, 100)
+		s.Require().NoError(err)
+		s.Require().Equal("package main", string(buf[:n]))
+		n, err = main.ReadContent(buf, 8, 100)
+		s.Require().NoError(err)
+		s.Require().Equal("main", string(buf[:n]))
+		n, err = main.ReadContent(buf, 100, 100)
+		s.Require().NoError(err)
+		s.Require().Zero(n)
+
+		s.Require().NoError(s.tree.SetParentAsCurrent())
+		s.Require().NoError(s.tree.SetParentAsCurrent())
+	}
+}
+
+func (s *ArchiveTestSuite) TestCollapseClosesArchive() {
+	s.selectChild("a.zip")
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	s.Require().NoError(s.tree.SetParentAsCurrent())
+
+	s.Require().NoError(s.tree.CollapseOrExpandSelected())
+	archive := s.tree.GetSelectedChild()
+	s.Require().Nil(archive.Children)
+	s.Require().False(archive.Info.IsDir())
+}
+
+func (s *ArchiveTestSuite) TestLeavingReleasesArchive() {
+	s.selectChild("a.zip")
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	archive := s.tree.CurrentDir.archive
+	s.Require().NotNil(archive.file)
+	s.selectChild("readme.txt")
+	s.tree.MarkSelectedChild()
+
+	s.Require().NoError(s.tree.SetParentAsCurrent())
+	s.Require().Nil(archive.file)
+
+	// Marked members are still readable, archive is reopened on demand.
+	s.Require().NoError(s.tree.CopyMarkedToCurrentDir())
+	s.Require().Equal("hello", readTestFile(s.T(), s.fsys, filepath.Join(s.dir, "readme.txt")))
+	s.Require().Zero(archive.readers)
+}
+
+func (s *ArchiveTestSuite) TestExtract() {
+	s.selectChild("b.tar.gz")
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	s.selectChild("src")
+	s.tree.MarkSelectedChild()
+	s.selectChild("readme.txt")
+	s.tree.MarkSelectedChild()
+
+	s.Require().ErrorIs(s.tree.CopyMarkedToCurrentDir(), errReadOnlyArchive, "can't paste into archive")
+	s.Require().Len(s.tree.Marked, 2)
+
+	s.Require().NoError(s.tree.SetParentAsCurrent())
+	s.Require().NoError(s.tree.CopyMarkedToCurrentDir())
+
+	s.Require().Equal("package pkg", readTestFile(s.T(), s.fsys, filepath.Join(s.dir, "src", "pkg", "util.go")))
+	info, err := s.fsys.Stat(filepath.Join(s.dir, "readme.txt"))
+	s.Require().NoError(err)
+	s.Require().Equal(os.FileMode(0o640), info.Mode().Perm())
+	requireNotExists(s.T(), s.fsys, filepath.Join(s.dir, "..", "escape.txt"))
+
+	s.Require().NoError(s.tree.Undo())
+	requireNotExists(s.T(), s.fsys, filepath.Join(s.dir, "src"))
+	s.Require().NoError(s.tree.Redo())
+	requireExists(s.T(), s.fsys, filepath.Join(s.dir, "src", "main.go"))
+}
+
+func (s *ArchiveTestSuite) TestReadOnly() {
+	s.selectChild("a.zip")
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	s.selectChild("readme.txt")
+
+	s.tree.MarkSelectedChild()
+	s.Require().ErrorIs(s.tree.DeleteMarkedPermanently(), errReadOnlyArchive)
+	s.tree.MarkSelectedChild()
+	s.Require().ErrorIs(s.tree.RenameMarked("other.txt"), errReadOnlyArchive)
+	s.Require().ErrorIs(s.tree.CreateFileInCurrent("new.txt"), errReadOnlyArchive)
+}
+
+func TestArchiveTestSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveTestSuite))
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package tree
+
+// Devices can't be distinguished, home trash is always used.
+func deviceOf(fsys FS, path string) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package tree
+
+import (
+	"syscall"
+)
+
+// Returns id of the device, containing path.
+func deviceOf(fsys FS, path string) (uint64, bool) {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
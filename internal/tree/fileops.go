@@ -0,0 +1,204 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// FileOpError describes a failed file operation on a single path.
+type FileOpError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *FileOpError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Op, e.Path, e.Err.Error())
+}
+func (e *FileOpError) Unwrap() error {
+	return e.Err
+}
+
+// FileOpErrors collects errors of an operation performed on multiple paths.
+type FileOpErrors []*FileOpError
+
+func (e FileOpErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+func (e FileOpErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Collects errors into FileOpErrors, returns nil if there are none.
+func collectOpErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	res := FileOpErrors{}
+	for _, err := range errs {
+		var opErr *FileOpError
+		if !errors.As(err, &opErr) {
+			opErr = &FileOpError{Op: "unknown", Err: err}
+		}
+		res = append(res, opErr)
+	}
+	return res
+}
+
+// Copies src from srcFS to dst on dstFS recursively. Modes, modification times and symlinks are preserved.
+// dst must not exist.
+func copyPath(srcFS FS, src string, dstFS FS, dst string) error {
+	if srcFS == dstFS && isSubPath(src, dst) {
+		return &FileOpError{Op: "copy", Path: src, Err: fmt.Errorf("can't copy directory into itself")}
+	}
+	return copyEntry(srcFS, src, dstFS, dst)
+}
+
+// Moves src from srcFS to dst on dstFS. When src and dst are on different filesystems or devices,
+// falls back to copying src and removing it afterwards.
+// dst must not exist.
+func movePath(srcFS FS, src string, dstFS FS, dst string) error {
+	if srcFS == dstFS && isSubPath(src, dst) {
+		return &FileOpError{Op: "move", Path: src, Err: fmt.Errorf("can't move directory into itself")}
+	}
+	if _, err := dstFS.Lstat(dst); err == nil {
+		return &FileOpError{Op: "move", Path: dst, Err: fs.ErrExist}
+	}
+	if srcFS == dstFS {
+		err := srcFS.Rename(src, dst)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EXDEV) {
+			return &FileOpError{Op: "move", Path: src, Err: unwrapPathErr(err)}
+		}
+	}
+	if err := copyEntry(srcFS, src, dstFS, dst); err != nil {
+		// Not leaving partial copy behind, source is still intact.
+		removeAll(dstFS, dst)
+		return err
+	}
+	return removePath(srcFS, src)
+}
+
+// Removes path and everything it contains.
+func removePath(fsys FS, path string) error {
+	if err := removeAll(fsys, path); err != nil {
+		return &FileOpError{Op: "remove", Path: path, Err: unwrapPathErr(err)}
+	}
+	return nil
+}
+
+func copyEntry(srcFS FS, src string, dstFS FS, dst string) error {
+	info, err := srcFS.Lstat(src)
+	if err != nil {
+		return &FileOpError{Op: "copy", Path: src, Err: unwrapPathErr(err)}
+	}
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		return copySymlink(srcFS, src, dstFS, dst)
+	case info.IsDir():
+		return copyDir(srcFS, src, dstFS, dst, info)
+	case info.Mode().IsRegular():
+		return copyFile(srcFS, src, dstFS, dst, info)
+	default:
+		return &FileOpError{Op: "copy", Path: src, Err: fmt.Errorf("unsupported file type %s", info.Mode().Type())}
+	}
+}
+
+func copySymlink(srcFS FS, src string, dstFS FS, dst string) error {
+	target, err := srcFS.Readlink(src)
+	if err != nil {
+		return &FileOpError{Op: "copy", Path: src, Err: unwrapPathErr(err)}
+	}
+	if err := dstFS.Symlink(target, dst); err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	return nil
+}
+
+func copyDir(srcFS FS, src string, dstFS FS, dst string, info fs.FileInfo) error {
+	// Creating with owner write permission, so read-only directories can be filled.
+	// Actual mode is set after all the content is copied.
+	if err := dstFS.Mkdir(dst, 0o700); err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	entries, err := srcFS.ReadDir(src)
+	if err != nil {
+		return &FileOpError{Op: "copy", Path: src, Err: unwrapPathErr(err)}
+	}
+	for _, e := range entries {
+		err := copyEntry(srcFS, filepath.Join(src, e.Name()), dstFS, filepath.Join(dst, e.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return copyMeta(dstFS, dst, info)
+}
+
+func copyFile(srcFS FS, src string, dstFS FS, dst string, info fs.FileInfo) error {
+	in, err := srcFS.Open(src)
+	if err != nil {
+		return &FileOpError{Op: "copy", Path: src, Err: unwrapPathErr(err)}
+	}
+	defer in.Close()
+
+	out, err := dstFS.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm()|0o200)
+	if err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	if err := out.Close(); err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	return copyMeta(dstFS, dst, info)
+}
+
+func copyMeta(fsys FS, dst string, info fs.FileInfo) error {
+	if err := fsys.Chmod(dst, info.Mode()&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)); err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	if err := fsys.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return &FileOpError{Op: "copy", Path: dst, Err: unwrapPathErr(err)}
+	}
+	return nil
+}
+
+// Checks if target is base itself or lies somewhere inside of it.
+func isSubPath(base, target string) bool {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// Path is already a part of FileOpError, so there is no need to repeat it.
+func unwrapPathErr(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return linkErr.Err
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package tree
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Simulates moves across devices.
+type exdevFS struct {
+	FS
+}
+
+func (exdevFS) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: syscall.EXDEV}
+}
+
+func TestCopyPathRecursive(t *testing.T) {
+	for name, fsys := range map[string]FS{"os": NewOSFS(), "mem": NewMemFS()} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, mkdirAll(fsys, dir, 0o755))
+			createTestFileTree(t, fsys, dir, testnode{
+				name: "src",
+				children: []testnode{
+					{name: "file.txt", isFile: true, content: []byte("content")},
+					{name: "sub", children: []testnode{
+						{name: "inner.txt", isFile: true, content: []byte("inner")},
+					}},
+				},
+			})
+			src := path.Join(dir, "src")
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			require.NoError(t, fsys.Chmod(path.Join(src, "file.txt"), 0o751))
+			require.NoError(t, fsys.Chtimes(path.Join(src, "file.txt"), mtime, mtime))
+			require.NoError(t, fsys.Symlink("file.txt", path.Join(src, "link")))
+			require.NoError(t, fsys.Chmod(path.Join(src, "sub"), 0o555))
+			t.Cleanup(func() { fsys.Chmod(path.Join(src, "sub"), 0o755) })
+
+			dst := path.Join(dir, "dst")
+			require.NoError(t, copyPath(fsys, src, fsys, dst))
+			t.Cleanup(func() { fsys.Chmod(path.Join(dst, "sub"), 0o755) })
+
+			require.Equal(t, "inner", readTestFile(t, fsys, path.Join(dst, "sub", "inner.txt")))
+
+			info, err := fsys.Stat(path.Join(dst, "file.txt"))
+			require.NoError(t, err)
+			require.Equal(t, fs.FileMode(0o751), info.Mode().Perm())
+			require.True(t, mtime.Equal(info.ModTime()))
+
+			info, err = fsys.Stat(path.Join(dst, "sub"))
+			require.NoError(t, err)
+			require.Equal(t, fs.FileMode(0o555), info.Mode().Perm())
+
+			target, err := fsys.Readlink(path.Join(dst, "link"))
+			require.NoError(t, err)
+			require.Equal(t, "file.txt", target)
+		})
+	}
+}
+
+func TestCopyPathIntoItself(t *testing.T) {
+	fsys := NewMemFS()
+	createTestFileTree(t, fsys, "/", testnode{name: "src"})
+
+	err := copyPath(fsys, "/src", fsys, "/src/copy_src")
+	var opErr *FileOpError
+	require.ErrorAs(t, err, &opErr)
+	require.Equal(t, "copy", opErr.Op)
+}
+
+func TestMovePathCrossDevice(t *testing.T) {
+	fsys := exdevFS{NewMemFS()}
+	createTestFileTree(t, fsys, "/", testnode{
+		name: "src",
+		children: []testnode{
+			{name: "file.txt", isFile: true, content: []byte("content")},
+		},
+	})
+	require.NoError(t, movePath(fsys, "/src", fsys, "/dst"))
+
+	requireNotExists(t, fsys, "/src")
+	require.Equal(t, "content", readTestFile(t, fsys, "/dst/file.txt"))
+}
+
+func TestMovePathError(t *testing.T) {
+	fsys := NewMemFS()
+	err := movePath(fsys, "/missing", fsys, "/dst")
+
+	var opErr *FileOpError
+	require.ErrorAs(t, err, &opErr)
+	require.Equal(t, "/missing", opErr.Path)
+	require.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,171 @@
+package tree
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/LeperGnome/bt/pkg/ignore"
+)
+
+const (
+	findBatchSize     = 512
+	findBatchInterval = 100 * time.Millisecond
+	findChanBuffer    = 4
+)
+
+type FindResult struct {
+	Path  string
+	IsDir bool
+}
+
+// FindBatch is a portion of results, streamed by a Finder.
+type FindBatch struct {
+	FinderID int
+	Results  []FindResult
+	Done     bool
+}
+
+// Finder walks file system in the background.
+type Finder struct {
+	ID      int
+	Batches <-chan FindBatch
+
+	cancel context.CancelFunc
+}
+
+func (f *Finder) Stop() {
+	f.cancel()
+}
+
+// Finders of all trees are numbered together, so their batches can't be confused.
+var lastFinderID atomic.Int64
+
+// Starts walking the tree root in background. Directories deeper than depthLimit are skipped.
+// Hidden files are skipped in directories, which hide them, and in all their unread subdirectories.
+// Files, matched by ignore files, are skipped if the tree respects them.
+func (t *Tree) StartFind(depthLimit int) *Finder {
+	// Snapshot of hidden settings, so walker doesn't touch the tree concurrently.
+	showHidden := map[string]bool{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		showHidden[n.Path] = n.showHidden
+		for _, ch := range n.Children {
+			if ch.Children != nil {
+				collect(ch)
+			}
+		}
+	}
+	collect(t.Root)
+	return t.startWalker([]walkRoot{t.Root.walkRoot()}, showHidden, depthLimit, 0)
+}
+
+type walkRoot struct {
+	path          string
+	showHidden    bool
+	respectIgnore bool
+	ignored       ignore.Matcher // patterns of parent directories, walker reads the rest
+}
+
+// Should be called from the tree's goroutine, as it reads ignore files of parents on demand.
+func (n *Node) walkRoot() walkRoot {
+	r := walkRoot{path: n.Path, showHidden: n.showHidden}
+	if n.respectIgnore && n.archive == nil {
+		r.respectIgnore = true
+		m := n.ignoreMatcher()
+		// Node's own patterns are the last ones, if it's read.
+		r.ignored = m[:len(m)-len(n.ignorePatterns)]
+	}
+	return r
+}
+
+// Walks roots one by one, stopping after limit results (0 for no limit).
+func (t *Tree) startWalker(roots []walkRoot, showHidden map[string]bool, depthLimit, limit int) *Finder {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan FindBatch, findChanBuffer)
+	f := &Finder{ID: int(lastFinderID.Add(1)), Batches: ch, cancel: cancel}
+
+	go func() {
+		defer close(ch)
+		w := findWalker{
+			fsys:       t.fsys,
+			ctx:        ctx,
+			id:         f.ID,
+			out:        ch,
+			showHidden: showHidden,
+			depthLimit: depthLimit,
+			limit:      limit,
+			lastSent:   time.Now(),
+		}
+		for _, root := range roots {
+			w.walk(root.path, root.showHidden, root.respectIgnore, root.ignored, 1)
+		}
+		w.flush(true)
+	}()
+	return f
+}
+
+type findWalker struct {
+	fsys       FS
+	ctx        context.Context
+	id         int
+	out        chan<- FindBatch
+	showHidden map[string]bool
+	depthLimit int
+	limit      int
+
+	found    int
+	batch    []FindResult
+	lastSent time.Time
+}
+
+func (w *findWalker) walk(dir string, showHidden, respectIgnore bool, ignored ignore.Matcher, depth int) {
+	if depth > w.depthLimit || w.ctx.Err() != nil {
+		return
+	}
+	if sh, ok := w.showHidden[dir]; ok {
+		showHidden = sh
+	}
+	entries, err := w.fsys.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	if respectIgnore {
+		// Clipped, so sibling directories don't overwrite each other's patterns.
+		ignored = append(slices.Clip(ignored), readIgnorePatterns(w.fsys, dir)...)
+	}
+	for _, e := range entries {
+		if w.limit > 0 && w.found >= w.limit {
+			return
+		}
+		if !showHidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		// Like in the tree, opened directories are kept.
+		if _, read := w.showHidden[p]; respectIgnore && !read && ignored.Match(filepath.ToSlash(p), e.IsDir()) {
+			continue
+		}
+		w.found++
+		w.batch = append(w.batch, FindResult{Path: p, IsDir: e.IsDir()})
+		if len(w.batch) >= findBatchSize || time.Since(w.lastSent) > findBatchInterval {
+			w.flush(false)
+		}
+		if e.IsDir() {
+			w.walk(p, showHidden, respectIgnore, ignored, depth+1)
+		}
+	}
+}
+
+func (w *findWalker) flush(done bool) {
+	select {
+	case w.out <- FindBatch{FinderID: w.id, Results: w.batch, Done: done}:
+	case <-w.ctx.Done():
+		// Nobody is listening anymore.
+	}
+	w.batch = nil
+	w.lastSent = time.Now()
+}
@@ -0,0 +1,41 @@
+package tree
+
+import (
+	"path/filepath"
+	"time"
+)
+
+func (s *TreeTestSuite) collectFind(depthLimit int) []string {
+	f := s.tree.StartFind(depthLimit)
+	paths := []string{}
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case batch := <-f.Batches:
+			s.Require().Equal(f.ID, batch.FinderID)
+			for _, res := range batch.Results {
+				rel, err := filepath.Rel(s.tree.Root.Path, res.Path)
+				s.Require().NoError(err)
+				paths = append(paths, rel)
+			}
+			if batch.Done {
+				return paths
+			}
+		case <-timeout:
+			s.FailNow("finder didn't finish in time")
+		}
+	}
+}
+
+func (s *TreeTestSuite) TestFind() {
+	paths := s.collectFind(16)
+	s.Require().ElementsMatch(
+		[]string{"empty_dir", "inner_dir", "inner_dir/inner_file", "testfile.txt"},
+		paths,
+	)
+}
+
+func (s *TreeTestSuite) TestFindDepthLimit() {
+	paths := s.collectFind(1)
+	s.Require().ElementsMatch([]string{"empty_dir", "inner_dir", "testfile.txt"}, paths)
+}
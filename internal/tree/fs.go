@@ -0,0 +1,93 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// FS is a file system, which tree is built upon.
+// Paths are absolute and use OS path separators.
+type FS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Mkdir(name string, perm fs.FileMode) error
+	Rename(oldname, newname string) error
+	Remove(name string) error
+	Readlink(name string) (string, error)
+	Symlink(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	// Creates watcher, reporting changes inside of added directories.
+	Watch() (Watcher, error)
+}
+
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Closer
+	Stat() (fs.FileInfo, error)
+}
+
+// Returns prefix of paths on a remote fsys, e.g. sftp://user@host, empty for local ones.
+func fsLocation(fsys FS) string {
+	if l, ok := fsys.(interface{ Location() string }); ok {
+		return l.Location()
+	}
+	return ""
+}
+
+// Creates directory along with all missing parents.
+func mkdirAll(fsys FS, path string, perm fs.FileMode) error {
+	if info, err := fsys.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := mkdirAll(fsys, parent, perm); err != nil {
+			return err
+		}
+	}
+	err := fsys.Mkdir(path, perm)
+	if errors.Is(err, fs.ErrExist) {
+		// Created concurrently.
+		return nil
+	}
+	return err
+}
+
+// Removes path and everything it contains. Missing path is not an error.
+func removeAll(fsys FS, path string) error {
+	info, err := fsys.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		entries, err := fsys.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := removeAll(fsys, filepath.Join(path, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	err = fsys.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
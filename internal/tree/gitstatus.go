@@ -0,0 +1,164 @@
+package tree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitState of a path in working tree. Greater states take precedence, when combined.
+type GitState int
+
+const (
+	GitClean GitState = iota
+	GitIgnored
+	GitUntracked
+	GitStaged
+	GitModified
+	GitConflicted
+)
+
+// GitStatus is a snapshot of `git status` of the repository, containing tree root.
+type GitStatus struct {
+	RepoRoot string
+
+	files map[string]GitState // changed paths
+	trees map[string]GitState // untracked or ignored directories, states apply to everything inside
+	dirty map[string]GitState // directories with changes inside, most severe state
+}
+
+// Returns state of the path. Directories report the most severe state of changes inside.
+func (g *GitStatus) State(path string) GitState {
+	if g == nil {
+		return GitClean
+	}
+	if st, ok := g.files[path]; ok {
+		return st
+	}
+	if st, ok := g.dirty[path]; ok {
+		return st
+	}
+	for dir := path; dir != g.RepoRoot && dir != filepath.Dir(dir); {
+		dir = filepath.Dir(dir)
+		if st, ok := g.trees[dir]; ok {
+			return st
+		}
+	}
+	return GitClean
+}
+
+// Directory has changes inside, but isn't changed as a whole.
+func (g *GitStatus) IsDirty(path string) bool {
+	if g == nil {
+		return false
+	}
+	_, changed := g.files[path]
+	_, dirty := g.dirty[path]
+	return dirty && !changed
+}
+
+// Reads status of the repository, containing dir, with git executable.
+// Returns nil status, when dir isn't inside a repository or git isn't installed.
+func ReadGitStatus(dir string) (*GitStatus, error) {
+	ctx := context.Background()
+	prefix, err := runGit(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.Is(err, exec.ErrNotFound) || errors.As(err, &exitErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Repository root is derived from dir, so paths match tree paths even if dir is reached through symlinks.
+	repoRoot := dir
+	if p := strings.Trim(strings.TrimSpace(string(prefix)), "/"); p != "" {
+		for range strings.Count(p, "/") + 1 {
+			repoRoot = filepath.Dir(repoRoot)
+		}
+	}
+	// Optional locks are skipped, so watched .git directory doesn't trigger refresh over and over.
+	out, err := runGit(ctx, dir, "--no-optional-locks", "status", "--porcelain=v1", "-z", "--ignored")
+	if err != nil {
+		return nil, err
+	}
+	return parseGitStatus(repoRoot, out), nil
+}
+
+// Reads unified diff of the file against HEAD. Falls back to the index in repositories without commits.
+// Git is killed, when ctx is done.
+func ReadGitDiff(ctx context.Context, path string) ([]byte, error) {
+	dir, name := filepath.Split(path)
+	args := []string{"--no-optional-locks", "diff", "--no-color", "--no-ext-diff"}
+	out, err := runGit(ctx, dir, append(args, "HEAD", "--", name)...)
+	if err != nil && ctx.Err() == nil {
+		if _, headErr := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); headErr != nil {
+			return runGit(ctx, dir, append(args, "--", name)...)
+		}
+	}
+	return out, err
+}
+
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("git: %w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+	return out, err
+}
+
+// Parses output of `git status --porcelain=v1 -z`, paths in it are relative to repoRoot.
+func parseGitStatus(repoRoot string, out []byte) *GitStatus {
+	g := &GitStatus{
+		RepoRoot: repoRoot,
+		files:    map[string]GitState{},
+		trees:    map[string]GitState{},
+		dirty:    map[string]GitState{},
+	}
+	entries := strings.Split(string(out), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, rel := entry[0], entry[1], entry[3:]
+		if x == 'R' || x == 'C' {
+			i++ // original path of a rename or copy follows
+		}
+		st := gitStateOf(x, y)
+		path := filepath.Join(repoRoot, filepath.FromSlash(rel))
+		if strings.HasSuffix(rel, "/") {
+			g.trees[path] = st
+		}
+		g.files[path] = st
+		if st == GitIgnored {
+			continue
+		}
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			g.dirty[dir] = max(g.dirty[dir], st)
+			if dir == repoRoot || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+	return g
+}
+
+func gitStateOf(x, y byte) GitState {
+	switch {
+	case x == '!':
+		return GitIgnored
+	case x == '?':
+		return GitUntracked
+	case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+		return GitConflicted
+	case y != ' ':
+		return GitModified
+	default:
+		return GitStaged
+	}
+}
@@ -0,0 +1,80 @@
+package tree
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	out := "M  staged.go\x00" +
+		" M sub/inner/modified.go\x00" +
+		"MM both.go\x00" +
+		"R  new.go\x00old.go\x00" +
+		"UU sub/conflict.go\x00" +
+		"?? untracked/\x00" +
+		"!! build/\x00"
+	g := parseGitStatus("/repo", []byte(out))
+
+	require.Equal(t, GitStaged, g.State("/repo/staged.go"))
+	require.Equal(t, GitModified, g.State("/repo/sub/inner/modified.go"))
+	require.Equal(t, GitModified, g.State("/repo/both.go"))
+	require.Equal(t, GitStaged, g.State("/repo/new.go"))
+	require.Equal(t, GitClean, g.State("/repo/old.go"))
+	require.Equal(t, GitConflicted, g.State("/repo/sub/conflict.go"))
+	require.Equal(t, GitUntracked, g.State("/repo/untracked/deep/file"))
+	require.Equal(t, GitIgnored, g.State("/repo/build/out"))
+	require.Equal(t, GitClean, g.State("/repo/clean.go"))
+
+	// Most severe state is propagated up to the repository root, ignored files don't make directories dirty.
+	require.Equal(t, GitConflicted, g.State("/repo/sub"))
+	require.Equal(t, GitModified, g.State("/repo/sub/inner"))
+	require.Equal(t, GitConflicted, g.State("/repo"))
+	require.True(t, g.IsDirty("/repo/sub"))
+	require.False(t, g.IsDirty("/repo/untracked"))
+	require.False(t, g.IsDirty("/"))
+}
+
+func TestReadGitStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=bt", "-c", "user.email=bt@localhost"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("v1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0o644))
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("v2"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "debug.log"), nil, 0o644))
+
+	// Read from a subdirectory, paths are still resolved against repository root.
+	g, err := ReadGitStatus(filepath.Join(dir, "sub"))
+	require.NoError(t, err)
+	require.Equal(t, dir, g.RepoRoot)
+	require.Equal(t, GitModified, g.State(filepath.Join(dir, "sub", "file.txt")))
+	require.Equal(t, GitUntracked, g.State(filepath.Join(dir, "new.txt")))
+	require.Equal(t, GitIgnored, g.State(filepath.Join(dir, "debug.log")))
+	require.True(t, g.IsDirty(filepath.Join(dir, "sub")))
+
+	diff, err := ReadGitDiff(context.Background(), filepath.Join(dir, "sub", "file.txt"))
+	require.NoError(t, err)
+	require.Contains(t, string(diff), "-v1")
+	require.Contains(t, string(diff), "+v2")
+
+	g, err = ReadGitStatus(t.TempDir())
+	require.NoError(t, err)
+	require.Nil(t, g)
+}
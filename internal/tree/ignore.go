@@ -0,0 +1,96 @@
+package tree
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/LeperGnome/bt/pkg/ignore"
+)
+
+// Ignore files of a directory, later ones take precedence.
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// Reads patterns of ignore files in dir. Repository root also has it's local excludes.
+func readIgnorePatterns(fsys FS, dir string) []ignore.Pattern {
+	patterns := []ignore.Pattern{}
+	base := filepath.ToSlash(dir)
+	names := ignoreFiles
+	if isRepoRoot(fsys, dir) {
+		names = append([]string{filepath.Join(".git", "info", "exclude")}, names...)
+	}
+	for _, name := range names {
+		f, err := fsys.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, ignore.Parse(f, base)...)
+		f.Close()
+	}
+	return patterns
+}
+func isRepoRoot(fsys FS, dir string) bool {
+	_, err := fsys.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// Combines patterns of the node and all it's parents, deeper ones take precedence.
+func (n *Node) ignoreMatcher() ignore.Matcher {
+	chain := []*Node{}
+	for node := n; node != nil; node = node.Parent {
+		chain = append(chain, node)
+	}
+	slices.Reverse(chain)
+	top := chain[0]
+	if top.outerIgnore == nil {
+		top.outerIgnore = readOuterIgnorePatterns(top.fsys, top.Path)
+	}
+	m := slices.Clone(ignore.Matcher(top.outerIgnore))
+	for _, node := range chain {
+		m = append(m, node.ignorePatterns...)
+	}
+	return m
+}
+
+// Reads patterns of directories above dir up to the repository root.
+// Outside of repositories only ignore files of dir itself apply.
+func readOuterIgnorePatterns(fsys FS, dir string) []ignore.Pattern {
+	dirs := []string{}
+	for d := dir; !isRepoRoot(fsys, d); {
+		parent := filepath.Dir(d)
+		if parent == d {
+			return []ignore.Pattern{}
+		}
+		d = parent
+		dirs = append(dirs, d)
+	}
+	patterns := []ignore.Pattern{}
+	for _, d := range slices.Backward(dirs) {
+		patterns = append(patterns, readIgnorePatterns(fsys, d)...)
+	}
+	return patterns
+}
+
+// Toggles hiding of children, matched by ignore files (.gitignore, .ignore and .git/info/exclude).
+func (t *Tree) ToggleIgnoreFiles() error {
+	t.respectIgnore = !t.respectIgnore
+	var toggle func(n *Node) error
+	toggle = func(n *Node) error {
+		n.respectIgnore = t.respectIgnore
+		if n.Children == nil {
+			return nil
+		}
+		if err := n.readChildren(t.sortingFunc); err != nil {
+			return err
+		}
+		for _, ch := range n.Children {
+			if err := toggle(ch); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return toggle(t.Root)
+}
+func (t *Tree) RespectsIgnoreFiles() bool {
+	return t.respectIgnore
+}
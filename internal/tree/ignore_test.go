@@ -0,0 +1,79 @@
+package tree
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createIgnoreTestRepo(t *testing.T) FS {
+	fsys := NewMemFS()
+	createTestFileTree(t, fsys, "/", testnode{
+		name: "repo",
+		children: []testnode{
+			{name: ".git", children: []testnode{
+				{name: "info", children: []testnode{
+					{name: "exclude", isFile: true, content: []byte("secret\n")},
+				}},
+			}},
+			{name: ".gitignore", isFile: true, content: []byte("build/\n*.log\n")},
+			{name: "sub", children: []testnode{
+				{name: ".ignore", isFile: true, content: []byte("!keep.log\n")},
+				{name: "build", children: []testnode{
+					{name: "out", isFile: true},
+				}},
+				{name: "nested", children: []testnode{
+					{name: "debug.log", isFile: true},
+					{name: "keep.log", isFile: true},
+					{name: "main.go", isFile: true},
+				}},
+				{name: "debug.log", isFile: true},
+				{name: "secret", isFile: true},
+				{name: "main.go", isFile: true},
+			}},
+		},
+	})
+	return fsys
+}
+
+func TestToggleIgnoreFiles(t *testing.T) {
+	fsys := createIgnoreTestRepo(t)
+	// Root is below repository root, so patterns above it apply too.
+	tree, _, err := InitTree(fsys, "/repo/sub", defaultNodeSorting)
+	require.NoError(t, err)
+	all := []string{"build", "nested", ".ignore", "debug.log", "main.go", "secret"}
+	require.Equal(t, all, nodeNames(tree.Root.Children))
+
+	require.NoError(t, tree.ToggleIgnoreFiles())
+	require.True(t, tree.RespectsIgnoreFiles())
+	require.Equal(t, []string{"nested", ".ignore", "main.go"}, nodeNames(tree.Root.Children))
+
+	nested := tree.Root.Children[0]
+	require.NoError(t, nested.readChildren(defaultNodeSorting))
+	require.Equal(t, []string{"keep.log", "main.go"}, nodeNames(nested.Children))
+
+	require.NoError(t, tree.ToggleIgnoreFiles())
+	require.Equal(t, all, nodeNames(tree.Root.Children))
+	require.Equal(t, []string{"debug.log", "keep.log", "main.go"}, nodeNames(nested.Children))
+}
+
+func TestFindRespectsIgnoreFiles(t *testing.T) {
+	tree, _, err := InitTree(createIgnoreTestRepo(t), "/repo/sub", defaultNodeSorting)
+	require.NoError(t, err)
+	require.NoError(t, tree.ToggleIgnoreFiles())
+
+	f := tree.StartFind(16)
+	paths := []string{}
+	for batch := range f.Batches {
+		for _, res := range batch.Results {
+			rel, err := filepath.Rel(tree.Root.Path, res.Path)
+			require.NoError(t, err)
+			paths = append(paths, rel)
+		}
+	}
+	require.ElementsMatch(t, []string{".ignore", "main.go", "nested", "nested/keep.log", "nested/main.go"}, paths)
+	for _, p := range paths {
+		require.NoError(t, tree.RevealPath(filepath.Join(tree.Root.Path, p)))
+	}
+}
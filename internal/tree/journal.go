@@ -0,0 +1,289 @@
+package tree
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const journalLimit = 100
+
+type journalOp int
+
+const (
+	opRename journalOp = iota
+	opCreate
+	opMove
+	opCopy
+	opDelete
+)
+
+func (o journalOp) String() string {
+	return []string{
+		"rename",
+		"create",
+		"move",
+		"copy",
+		"delete",
+	}[o]
+}
+
+// Paths, that must stay untouched for operation to be safely reversed.
+// Source of a copy may be changed freely, as well as a parent of created file.
+func (o journalOp) guardsSrc() bool {
+	return o != opCopy && o != opCreate
+}
+
+// Snapshot of a path, used to detect changes made outside of bt.
+type pathState struct {
+	fsys    FS
+	path    string
+	exists  bool
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+}
+
+func statPath(fsys FS, path string) pathState {
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return pathState{fsys: fsys, path: path}
+	}
+	return pathState{
+		fsys:    fsys,
+		path:    path,
+		exists:  true,
+		mode:    info.Mode(),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+func (s pathState) equal(o pathState) bool {
+	return s.path == o.path &&
+		s.exists == o.exists &&
+		s.mode == o.mode &&
+		s.size == o.size &&
+		s.modTime.Equal(o.modTime)
+}
+
+type journalItem struct {
+	src     string // empty for create
+	srcFS   FS     // differs from tree's one, when moved or copied from another root
+	dst     string // for delete - path inside trash
+	isDir   bool   // used to re-create directories
+	trashed TrashedItem
+	// Archive member, extracted to dst. It's src doesn't exist on disk.
+	extracted *Node
+	guards    []pathState
+}
+
+type journalEntry struct {
+	op    journalOp
+	items []*journalItem
+	stale string // path, which was changed outside of bt
+}
+
+// Remembers states of all guarded paths after entry was applied or reverted.
+func (e *journalEntry) snapshot(fsys FS) {
+	for _, item := range e.items {
+		item.guards = nil
+		if e.op.guardsSrc() {
+			item.guards = append(item.guards, statPath(item.srcFS, item.src))
+		}
+		item.guards = append(item.guards, statPath(fsys, item.dst))
+	}
+}
+
+// Returns first guarded path, which differs from the snapshot.
+func (e *journalEntry) changedPath() (string, bool) {
+	if e.stale != "" {
+		return e.stale, true
+	}
+	for _, item := range e.items {
+		for _, g := range item.guards {
+			if !statPath(g.fsys, g.path).equal(g) {
+				return g.path, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (e *journalEntry) describe() string {
+	names := []string{}
+	for _, item := range e.items {
+		p := item.src
+		if p == "" {
+			p = item.dst
+		}
+		names = append(names, filepath.Base(p))
+	}
+	return fmt.Sprintf("%s of %s", e.op, strings.Join(names, ", "))
+}
+
+// Journal keeps history of file operations to undo and redo them.
+type Journal struct {
+	fsys FS
+	undo []*journalEntry
+	redo []*journalEntry
+}
+
+func (j *Journal) record(op journalOp, items []*journalItem) {
+	if len(items) == 0 {
+		return
+	}
+	for _, item := range items {
+		if item.srcFS == nil {
+			item.srcFS = j.fsys
+		}
+	}
+	e := &journalEntry{op: op, items: items}
+	e.snapshot(j.fsys)
+	j.undo = append(j.undo, e)
+	if len(j.undo) > journalLimit {
+		j.undo = j.undo[len(j.undo)-journalLimit:]
+	}
+	j.redo = nil
+}
+
+// Forgets undone entries. Used after changes, which can't be recorded,
+// so redo won't be applied on top of them.
+func (j *Journal) dropRedo() {
+	j.redo = nil
+}
+
+// Marks entries, affected by a change of path, as stale.
+// Changes made by bt itself match snapshots and don't make entries stale.
+func (j *Journal) observe(path string) {
+	for _, e := range slices.Concat(j.undo, j.redo) {
+		if e.stale != "" {
+			continue
+		}
+	items:
+		for _, item := range e.items {
+			for _, g := range item.guards {
+				if !isSubPath(path, g.path) && !isSubPath(g.path, path) {
+					continue
+				}
+				if !statPath(g.fsys, g.path).equal(g) {
+					e.stale = g.path
+					break items
+				}
+			}
+		}
+	}
+}
+
+func (t *Tree) Undo() error {
+	j := t.journal
+	if len(j.undo) == 0 {
+		return fmt.Errorf("nothing to undo")
+	}
+	e := j.undo[len(j.undo)-1]
+	if p, changed := e.changedPath(); changed {
+		return fmt.Errorf("can't undo %s: %s was changed outside of bt", e.describe(), p)
+	}
+	if err := t.revertEntry(e); err != nil {
+		return fmt.Errorf("undo %s failed: %w", e.describe(), err)
+	}
+	j.undo = j.undo[:len(j.undo)-1]
+	e.snapshot(j.fsys)
+	j.redo = append(j.redo, e)
+	return nil
+}
+
+func (t *Tree) Redo() error {
+	j := t.journal
+	if len(j.redo) == 0 {
+		return fmt.Errorf("nothing to redo")
+	}
+	e := j.redo[len(j.redo)-1]
+	if p, changed := e.changedPath(); changed {
+		return fmt.Errorf("can't redo %s: %s was changed outside of bt", e.describe(), p)
+	}
+	if err := t.reapplyEntry(e); err != nil {
+		return fmt.Errorf("redo %s failed: %w", e.describe(), err)
+	}
+	j.redo = j.redo[:len(j.redo)-1]
+	e.snapshot(j.fsys)
+	j.undo = append(j.undo, e)
+	return nil
+}
+
+// Should be called on every file system change, so undo won't override changes made outside of bt.
+func (t *Tree) ObserveChange(path string) {
+	t.journal.observe(path)
+}
+
+func (t *Tree) revertEntry(e *journalEntry) error {
+	for i := len(e.items) - 1; i >= 0; i-- {
+		item := e.items[i]
+		var err error
+		switch e.op {
+		case opRename, opMove:
+			err = movePath(t.fsys, item.dst, item.srcFS, item.src)
+		case opCreate:
+			if item.isDir {
+				err = t.fsys.Remove(item.dst)
+			} else {
+				err = removePath(t.fsys, item.dst)
+			}
+		case opCopy:
+			err = removePath(t.fsys, item.dst)
+		case opDelete:
+			if t.trash == nil {
+				return fmt.Errorf("trash is not available")
+			}
+			err = t.trash.Restore(item.trashed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tree) reapplyEntry(e *journalEntry) error {
+	for _, item := range e.items {
+		var err error
+		switch e.op {
+		case opRename, opMove:
+			err = movePath(item.srcFS, item.src, t.fsys, item.dst)
+		case opCreate:
+			err = createPath(t.fsys, item.dst, item.isDir)
+		case opCopy:
+			if item.extracted != nil {
+				err = extractNode(t.fsys, item.extracted, item.dst)
+			} else {
+				err = copyPath(item.srcFS, item.src, t.fsys, item.dst)
+			}
+		case opDelete:
+			if t.trash == nil {
+				return fmt.Errorf("trash is not available")
+			}
+			item.trashed, err = t.trash.Put(item.src)
+			item.dst = item.trashed.TrashPath
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Creates empty file or directory. Fails if path already exists.
+func createPath(fsys FS, path string, isDir bool) error {
+	if isDir {
+		return fsys.Mkdir(path, os.ModePerm)
+	}
+	f, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,95 @@
+package tree
+
+import (
+	"path"
+)
+
+func (s *TreeTestSuite) TestUndoRedoMove() {
+	// Moving testfile.txt to empty_dir
+	s.tree.CurrentDir.SelectLast()
+	src := s.tree.GetSelectedChild().Path
+	s.Require().True(s.tree.MarkSelectedChild())
+	s.tree.CurrentDir.SelectFirst()
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	s.Require().NoError(s.tree.MoveMarkedToCurrentDir())
+
+	dst := path.Join(s.tree.CurrentDir.Path, "testfile.txt")
+	requireExists(s.T(), s.fsys, dst)
+
+	s.Require().NoError(s.tree.Undo())
+	requireExists(s.T(), s.fsys, src)
+	requireNotExists(s.T(), s.fsys, dst)
+
+	s.Require().NoError(s.tree.Redo())
+	requireExists(s.T(), s.fsys, dst)
+	requireNotExists(s.T(), s.fsys, src)
+}
+
+func (s *TreeTestSuite) TestUndoDeleteRestoresFromTrash() {
+	s.tree.CurrentDir.SelectLast()
+	src := s.tree.GetSelectedChild().Path
+	s.Require().True(s.tree.MarkSelectedChild())
+	s.Require().NoError(s.tree.DeleteMarked())
+	requireNotExists(s.T(), s.fsys, src)
+
+	s.Require().NoError(s.tree.Undo())
+	s.Require().Equal("some content", readTestFile(s.T(), s.fsys, src))
+
+	s.Require().Error(s.tree.Undo()) // nothing to undo
+}
+
+func (s *TreeTestSuite) TestUndoCopyAndCreate() {
+	s.Require().NoError(s.tree.CreateFileInCurrent("new_file"))
+	created := path.Join(s.tree.Root.Path, "new_file")
+	requireExists(s.T(), s.fsys, created)
+
+	s.tree.CurrentDir.SelectLast()
+	s.Require().True(s.tree.MarkSelectedChild())
+	s.Require().NoError(s.tree.CopyMarkedToCurrentDir())
+	copied := path.Join(s.tree.Root.Path, "copy_testfile.txt")
+	requireExists(s.T(), s.fsys, copied)
+
+	s.Require().NoError(s.tree.Undo())
+	requireNotExists(s.T(), s.fsys, copied)
+	s.Require().NoError(s.tree.Undo())
+	requireNotExists(s.T(), s.fsys, created)
+}
+
+func (s *TreeTestSuite) TestUndoRefusedAfterExternalChange() {
+	s.Require().NoError(s.tree.CreateFileInCurrent("new_file"))
+	created := path.Join(s.tree.Root.Path, "new_file")
+
+	// Simulating a change made by another program.
+	writeTestFile(s.T(), s.fsys, created, []byte("important"))
+	s.tree.ObserveChange(created)
+
+	s.Require().ErrorContains(s.tree.Undo(), "changed outside of bt")
+	requireExists(s.T(), s.fsys, created)
+}
+
+func (s *TreeTestSuite) TestRefusedUndoKeepsEntry() {
+	s.Require().NoError(s.tree.CreateFileInCurrent("first"))
+	s.Require().NoError(s.tree.CreateFileInCurrent("second"))
+	first := path.Join(s.tree.Root.Path, "first")
+	second := path.Join(s.tree.Root.Path, "second")
+
+	writeTestFile(s.T(), s.fsys, second, []byte("important"))
+	s.tree.ObserveChange(second)
+
+	s.Require().ErrorContains(s.tree.Undo(), "changed outside of bt")
+	// Older entry must not be reverted out of order.
+	s.Require().ErrorContains(s.tree.Undo(), "changed outside of bt")
+	requireExists(s.T(), s.fsys, first)
+	requireExists(s.T(), s.fsys, second)
+}
+
+func (s *TreeTestSuite) TestPermanentDeleteDropsRedo() {
+	s.Require().NoError(s.tree.CreateFileInCurrent("new_file"))
+	s.Require().NoError(s.tree.Undo())
+
+	s.tree.CurrentDir.SelectLast()
+	s.Require().True(s.tree.MarkSelectedChild())
+	s.Require().NoError(s.tree.DeleteMarkedPermanently())
+
+	s.Require().ErrorContains(s.tree.Redo(), "nothing to redo")
+}
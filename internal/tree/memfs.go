@@ -0,0 +1,479 @@
+package tree
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const memSymlinkDepth = 40
+
+// In-memory file system. Permissions are stored, but not enforced.
+// Symlinks are resolved only as the last element of a path.
+type memFS struct {
+	mu       sync.Mutex
+	nodes    map[string]*memNode
+	watchers []*memWatcher
+}
+
+type memNode struct {
+	mode    fs.FileMode
+	modTime time.Time
+	data    []byte
+	target  string // for symlinks
+}
+
+func NewMemFS() FS {
+	return &memFS{nodes: map[string]*memNode{
+		string(filepath.Separator): {mode: fs.ModeDir | 0o755, modTime: time.Now()},
+	}}
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, n, err := m.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	entries := []fs.DirEntry{}
+	for _, p := range m.children(name) {
+		entries = append(entries, fs.FileInfoToDirEntry(m.nodes[p].info(p)))
+	}
+	return entries, nil
+}
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, n, err := m.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(name), nil
+}
+func (m *memFS) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, n, err := m.lstat("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(name), nil
+}
+func (m *memFS) Open(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+func (m *memFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	name, n, err := m.stat("open", name)
+	switch {
+	case err == nil:
+		if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		if n.mode.IsDir() && writable {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: syscall.EISDIR}
+		}
+		if flag&os.O_TRUNC != 0 && writable {
+			n.data = nil
+			n.modTime = time.Now()
+		}
+	case errors.Is(err, fs.ErrNotExist) && flag&os.O_CREATE != 0:
+		if err := m.checkParent("open", name); err != nil {
+			return nil, err
+		}
+		n = &memNode{mode: perm & fs.ModePerm, modTime: time.Now()}
+		m.nodes[name] = n
+		m.notify(name)
+	default:
+		return nil, err
+	}
+	return &memFile{fsys: m, name: name, node: n, flag: flag}, nil
+}
+func (m *memFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = memClean(name)
+	if _, ok := m.nodes[name]; ok {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := m.checkParent("mkdir", name); err != nil {
+		return err
+	}
+	m.nodes[name] = &memNode{mode: fs.ModeDir | perm&fs.ModePerm, modTime: time.Now()}
+	m.notify(name)
+	return nil
+}
+func (m *memFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldname, newname = memClean(oldname), memClean(newname)
+	linkErr := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	n, ok := m.nodes[oldname]
+	if !ok {
+		return linkErr(fs.ErrNotExist)
+	}
+	if oldname == newname {
+		return nil
+	}
+	if err := m.checkParent("rename", newname); err != nil {
+		return linkErr(unwrapPathErr(err))
+	}
+	if n.mode.IsDir() && isSubPath(oldname, newname) {
+		return linkErr(syscall.EINVAL)
+	}
+	if dst, ok := m.nodes[newname]; ok {
+		switch {
+		case dst.mode.IsDir() && !n.mode.IsDir():
+			return linkErr(syscall.EISDIR)
+		case !dst.mode.IsDir() && n.mode.IsDir():
+			return linkErr(syscall.ENOTDIR)
+		case len(m.children(newname)) > 0:
+			return linkErr(syscall.ENOTEMPTY)
+		}
+	}
+	moved := map[string]*memNode{newname: n}
+	delete(m.nodes, oldname)
+	prefix := memChildPrefix(oldname)
+	for p, pn := range m.nodes {
+		if strings.HasPrefix(p, prefix) {
+			moved[filepath.Join(newname, p[len(prefix):])] = pn
+			delete(m.nodes, p)
+		}
+	}
+	for p, pn := range moved {
+		m.nodes[p] = pn
+	}
+	m.notify(oldname)
+	m.notify(newname)
+	return nil
+}
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, _, err := m.lstat("remove", name)
+	if err != nil {
+		return err
+	}
+	if name == string(filepath.Separator) {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.EBUSY}
+	}
+	if len(m.children(name)) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.nodes, name)
+	m.notify(name)
+	return nil
+}
+func (m *memFS) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, n, err := m.lstat("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if n.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: syscall.EINVAL}
+	}
+	return n.target, nil
+}
+func (m *memFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	newname = memClean(newname)
+	if _, ok := m.nodes[newname]; ok {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	if err := m.checkParent("symlink", newname); err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: unwrapPathErr(err)}
+	}
+	m.nodes[newname] = &memNode{mode: fs.ModeSymlink | fs.ModePerm, modTime: time.Now(), target: oldname}
+	m.notify(newname)
+	return nil
+}
+func (m *memFS) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, n, err := m.stat("chmod", name)
+	if err != nil {
+		return err
+	}
+	n.mode = n.mode.Type() | mode&(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky)
+	return nil
+}
+func (m *memFS) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, n, err := m.stat("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.modTime = mtime
+	return nil
+}
+func (m *memFS) Watch() (Watcher, error) {
+	w := &memWatcher{
+		fsys:    m,
+		dirs:    map[string]bool{},
+		wake:    make(chan struct{}, 1),
+		changes: make(chan NodeChange),
+		done:    make(chan struct{}),
+	}
+	m.mu.Lock()
+	m.watchers = append(m.watchers, w)
+	m.mu.Unlock()
+	go w.run()
+	return w, nil
+}
+
+// Following methods expect m.mu to be held.
+
+func (m *memFS) lstat(op, name string) (string, *memNode, error) {
+	name = memClean(name)
+	n, ok := m.nodes[name]
+	if !ok {
+		return name, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return name, n, nil
+}
+
+// Resolves symlinks. On error returned path is the last one, that was looked up.
+func (m *memFS) stat(op, name string) (string, *memNode, error) {
+	for range memSymlinkDepth {
+		p, n, err := m.lstat(op, name)
+		if err != nil || n.mode&fs.ModeSymlink == 0 {
+			return p, n, err
+		}
+		name = n.target
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(p), name)
+		}
+	}
+	return name, nil, &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+}
+func (m *memFS) checkParent(op, name string) error {
+	parent := filepath.Dir(name)
+	n, ok := m.nodes[parent]
+	if !ok {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !n.mode.IsDir() {
+		return &fs.PathError{Op: op, Path: name, Err: syscall.ENOTDIR}
+	}
+	return nil
+}
+
+// Returns sorted paths of direct children of dir.
+func (m *memFS) children(dir string) []string {
+	res := []string{}
+	for p := range m.nodes {
+		if p != dir && filepath.Dir(p) == dir {
+			res = append(res, p)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+func (m *memFS) notify(name string) {
+	for _, w := range m.watchers {
+		if w.dirs[filepath.Dir(name)] || w.dirs[name] {
+			w.push(NodeChange{Path: name})
+		}
+	}
+}
+
+func memClean(name string) string {
+	return filepath.Join(string(filepath.Separator), name)
+}
+func memChildPrefix(dir string) string {
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		return dir
+	}
+	return dir + string(filepath.Separator)
+}
+
+func (n *memNode) info(name string) fs.FileInfo {
+	size := int64(len(n.data))
+	if n.mode&fs.ModeSymlink != 0 {
+		size = int64(len(n.target))
+	}
+	return memInfo{name: filepath.Base(name), size: size, mode: n.mode, modTime: n.modTime}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+type memFile struct {
+	fsys   *memFS
+	name   string
+	node   *memNode
+	flag   int
+	offset int64
+	dirty  bool
+	closed bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	n, err := f.readAt("read", p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	n, err := f.readAt("read", p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+func (f *memFile) readAt(op string, p []byte, off int64) (int, error) {
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	case f.flag&os.O_WRONLY != 0:
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: syscall.EBADF}
+	case f.node.mode.IsDir():
+		return 0, &fs.PathError{Op: op, Path: f.name, Err: syscall.EISDIR}
+	case off >= int64(len(f.node.data)):
+		return 0, io.EOF
+	}
+	return copy(p, f.node.data[off:]), nil
+}
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	switch {
+	case f.closed:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	case f.flag&(os.O_WRONLY|os.O_RDWR) == 0:
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: syscall.EBADF}
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.offset = int64(len(f.node.data))
+	}
+	end := f.offset + int64(len(p))
+	if grow := end - int64(len(f.node.data)); grow > 0 {
+		f.node.data = append(f.node.data, make([]byte, grow)...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = time.Now()
+	f.dirty = true
+	return len(p), nil
+}
+func (f *memFile) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.dirty {
+		f.fsys.notify(f.name)
+	}
+	return nil
+}
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	return f.node.info(f.name), nil
+}
+
+// Changes are queued, so file system operations never wait for a reader.
+type memWatcher struct {
+	fsys *memFS
+	dirs map[string]bool // guarded by fsys.mu
+
+	mu      sync.Mutex
+	queue   []NodeChange
+	wake    chan struct{}
+	changes chan NodeChange
+	done    chan struct{}
+	close   sync.Once
+}
+
+func (w *memWatcher) Add(name string) error {
+	w.fsys.mu.Lock()
+	defer w.fsys.mu.Unlock()
+	name, _, err := w.fsys.stat("watch", name)
+	if err != nil {
+		return err
+	}
+	w.dirs[name] = true
+	return nil
+}
+func (w *memWatcher) Remove(name string) error {
+	w.fsys.mu.Lock()
+	defer w.fsys.mu.Unlock()
+	delete(w.dirs, memClean(name))
+	return nil
+}
+func (w *memWatcher) Changes() <-chan NodeChange {
+	return w.changes
+}
+func (w *memWatcher) Close() error {
+	w.close.Do(func() {
+		w.fsys.mu.Lock()
+		w.fsys.watchers = slices.DeleteFunc(w.fsys.watchers, func(o *memWatcher) bool { return o == w })
+		w.fsys.mu.Unlock()
+		close(w.done)
+	})
+	return nil
+}
+func (w *memWatcher) push(c NodeChange) {
+	w.mu.Lock()
+	w.queue = append(w.queue, c)
+	w.mu.Unlock()
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+func (w *memWatcher) run() {
+	for {
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+		for _, c := range queue {
+			select {
+			case w.changes <- c:
+			case <-w.done:
+				return
+			}
+		}
+		select {
+		case <-w.wake:
+		case <-w.done:
+			return
+		}
+	}
+}
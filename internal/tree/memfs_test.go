@@ -0,0 +1,100 @@
+package tree
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemFSRenameDirectory(t *testing.T) {
+	fsys := NewMemFS()
+	createTestFileTree(t, fsys, "/", testnode{
+		name: "src",
+		children: []testnode{
+			{name: "sub", children: []testnode{
+				{name: "file.txt", isFile: true, content: []byte("content")},
+			}},
+		},
+	})
+	require.NoError(t, fsys.Mkdir("/dst", 0o755))
+
+	require.NoError(t, fsys.Rename("/src", "/dst/moved"))
+	requireNotExists(t, fsys, "/src/sub")
+	require.Equal(t, "content", readTestFile(t, fsys, "/dst/moved/sub/file.txt"))
+
+	require.ErrorIs(t, fsys.Rename("/dst", "/dst/moved/inner"), syscall.EINVAL)
+	require.ErrorIs(t, fsys.Remove("/dst"), syscall.ENOTEMPTY)
+	require.ErrorIs(t, fsys.Rename("/missing", "/other"), fs.ErrNotExist)
+}
+
+func TestMemFSFiles(t *testing.T) {
+	fsys := NewMemFS()
+	writeTestFile(t, fsys, "/b.txt", []byte("hello"))
+	require.NoError(t, fsys.Mkdir("/a", 0o755))
+	require.NoError(t, fsys.Symlink("b.txt", "/link"))
+
+	_, err := fsys.OpenFile("/b.txt", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	require.ErrorIs(t, err, fs.ErrExist)
+	_, err = fsys.OpenFile("/missing/c.txt", os.O_WRONLY|os.O_CREATE, 0o644)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	f, err := fsys.OpenFile("/b.txt", os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte(" world"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "hello world", readTestFile(t, fsys, "/link"))
+
+	info, err := fsys.Lstat("/link")
+	require.NoError(t, err)
+	require.Equal(t, fs.ModeSymlink, info.Mode().Type())
+	info, err = fsys.Stat("/link")
+	require.NoError(t, err)
+	require.Equal(t, int64(11), info.Size())
+
+	entries, err := fsys.ReadDir("/")
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	require.Equal(t, []string{"a", "b.txt", "link"}, names)
+}
+
+func TestMemFSWatch(t *testing.T) {
+	fsys := NewMemFS()
+	require.NoError(t, fsys.Mkdir("/watched", 0o755))
+	require.NoError(t, fsys.Mkdir("/other", 0o755))
+
+	w, err := fsys.Watch()
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.Add("/watched"))
+
+	// Changes are queued until they're read.
+	writeTestFile(t, fsys, "/other/skipped.txt", nil)
+	writeTestFile(t, fsys, "/watched/file.txt", []byte("content"))
+	require.NoError(t, fsys.Rename("/watched/file.txt", "/other/file.txt"))
+
+	expected := []string{"/watched/file.txt", "/watched/file.txt", "/watched/file.txt"}
+	for _, p := range expected {
+		select {
+		case c := <-w.Changes():
+			require.Equal(t, p, c.Path)
+		case <-time.After(time.Second):
+			require.FailNow(t, "change was not reported")
+		}
+	}
+
+	require.NoError(t, w.Remove("/watched"))
+	writeTestFile(t, fsys, "/watched/unwatched.txt", nil)
+	select {
+	case c := <-w.Changes():
+		require.FailNow(t, "unexpected change", c.Path)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
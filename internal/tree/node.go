@@ -1,13 +1,16 @@
 package tree
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/fs"
-	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
+
+	"github.com/LeperGnome/bt/pkg/ignore"
 )
 
 type NodeSortingFunc func(a, b *Node) int
@@ -18,8 +21,15 @@ type Node struct {
 	Children []*Node // nil - not read or it's a file
 	Parent   *Node
 
+	fsys             FS
 	selectedChildIdx int
 	showHidden       bool
+	respectIgnore    bool             // hide children, matched by ignore files
+	ignorePatterns   []ignore.Pattern // of ignore files in this directory
+	outerIgnore      []ignore.Pattern // of ignore files above root up to repository root, nil until read
+
+	archive       *Archive // set for opened archives and their members
+	archiveMember string   // path inside archive, empty for archive itself
 }
 
 func (n *Node) SelectLast() {
@@ -34,21 +44,131 @@ func (n *Node) ShowsHidden() bool {
 func (n *Node) SelectFirst() {
 	n.selectedChildIdx = 0
 }
-func (n *Node) readChildren(sortFunc NodeSortingFunc) error {
-	if !n.Info.IsDir() {
-		return nil
+
+// Checks if node is an archive member, which doesn't exist on disk.
+func (n *Node) IsVirtual() bool {
+	return n.archive != nil && n.archiveMember != ""
+}
+
+// Checks if node is on the local disk, so it can be passed to other programs.
+func (n *Node) IsLocal() bool {
+	_, ok := n.fsys.(osFS)
+	return ok && !n.IsVirtual()
+}
+
+// Checks if node is on a remote file system, where walking is slow.
+func (n *Node) IsRemote() bool {
+	return fsLocation(n.fsys) != ""
+}
+
+// Path, prefixed with location of remote file system.
+func (n *Node) URL() string {
+	return fsLocation(n.fsys) + n.Path
+}
+
+// Opens node content for reading.
+func (n *Node) Open() (io.ReadCloser, error) {
+	if n.IsVirtual() {
+		return n.archive.open(n.archiveMember)
 	}
-	children, err := os.ReadDir(n.Path)
+	return n.fsys.Open(n.Path)
+}
+
+// Opens archive file, so it's members are read as children.
+func (n *Node) openArchive() error {
+	a, err := OpenArchive(n.fsys, n.Path)
 	if err != nil {
 		return err
 	}
-	chNodes := []*Node{}
+	n.archive = a
+	n.Info = archiveRootInfo{n.Info}
+	return nil
+}
+func (n *Node) closeArchive() {
+	n.archive.Close()
+	n.archive = nil
+	n.Info = n.Info.(archiveRootInfo).FileInfo
+}
+func (n *Node) readArchiveChildren() []fs.FileInfo {
+	return n.archive.readDir(n.archiveMember)
+}
 
+// Lists directory entries without changing the node, so it's safe to call in background.
+func (n *Node) ReadDir() ([]fs.FileInfo, error) {
+	if n.archive != nil {
+		return n.readArchiveChildren(), nil
+	}
+	return n.listDir(n.Path)
+}
+func (n *Node) listDir(dir string) ([]fs.FileInfo, error) {
+	children, err := n.fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := []fs.FileInfo{}
 	for _, ch := range children {
 		chInfo, err := ch.Info()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		infos = append(infos, chInfo)
+	}
+	return infos, nil
+}
+
+// Sums sizes of files inside the directory recursively, symlinks aren't followed.
+// At most limit entries are visited, false is returned, when the limit was hit or ctx is done.
+func (n *Node) DiskUsage(ctx context.Context, limit int) (int64, bool) {
+	list := n.listDir
+	if n.archive != nil {
+		list = func(member string) ([]fs.FileInfo, error) { return n.archive.readDir(member), nil }
+	}
+	var size int64
+	visited := 0
+	dirs := []string{n.Path}
+	if n.archive != nil {
+		dirs = []string{n.archiveMember}
+	}
+	for len(dirs) > 0 {
+		if ctx.Err() != nil {
+			return size, false
+		}
+		dir := dirs[len(dirs)-1]
+		dirs = dirs[:len(dirs)-1]
+		infos, err := list(dir)
+		if err != nil {
+			continue // unreadable directories are skipped
 		}
+		for _, info := range infos {
+			if visited++; visited > limit {
+				return size, false
+			}
+			if info.Mode().IsDir() {
+				dirs = append(dirs, path.Join(dir, info.Name()))
+			} else {
+				size += info.Size()
+			}
+		}
+	}
+	return size, true
+}
+
+func (n *Node) readChildren(sortFunc NodeSortingFunc) error {
+	if !n.Info.IsDir() {
+		return nil
+	}
+	infos, err := n.ReadDir()
+	if err != nil {
+		return err
+	}
+	var ignored ignore.Matcher
+	if n.respectIgnore && n.archive == nil {
+		n.ignorePatterns = readIgnorePatterns(n.fsys, n.Path)
+		ignored = n.ignoreMatcher()
+	}
+	chNodes := []*Node{}
+
+	for _, chInfo := range infos {
 		// Skipping hidden node
 		if !n.showHidden && strings.HasPrefix(chInfo.Name(), ".") {
 			continue
@@ -59,17 +179,29 @@ func (n *Node) readChildren(sortFunc NodeSortingFunc) error {
 			for _, ech := range n.Children {
 				if ech.Info.Name() == chInfo.Name() {
 					childToAdd = ech
+					if _, ok := ech.Info.(archiveRootInfo); ok {
+						chInfo = archiveRootInfo{chInfo}
+					}
 					childToAdd.Info = chInfo // updating info in case file was changed
 					break
 				}
 			}
 		}
+		// Opened directories are kept, so current directory isn't lost.
+		if ignored != nil && (childToAdd == nil || childToAdd.Children == nil) &&
+			ignored.Match(filepath.ToSlash(filepath.Join(n.Path, chInfo.Name())), chInfo.IsDir()) {
+			continue
+		}
 		if childToAdd == nil {
 			childToAdd = NewNode(
 				filepath.Join(n.Path, chInfo.Name()),
 				chInfo,
 				n,
 			)
+			if n.archive != nil {
+				childToAdd.archive = n.archive
+				childToAdd.archiveMember = path.Join(n.archiveMember, chInfo.Name())
+			}
 		}
 		chNodes = append(chNodes, childToAdd)
 	}
@@ -82,42 +214,82 @@ func (n *Node) readChildren(sortFunc NodeSortingFunc) error {
 }
 func (n *Node) orphanChildren() {
 	n.Children = nil
+	// Collapsed archive becomes a regular file again.
+	if n.archive != nil && !n.IsVirtual() {
+		n.closeArchive()
+	}
 }
-func (n *Node) ReadContent(buf []byte, limit int64) (int, error) {
+
+// Reads up to limit bytes of content, starting at offset. Reading past the end gives nothing.
+func (n *Node) ReadContent(buf []byte, offset, limit int64) (int, error) {
 	if !n.Info.Mode().IsRegular() {
 		return 0, fmt.Errorf("file not selected or is irregular")
 	}
-	f, err := os.Open(n.Path)
+	f, err := n.Open()
 	if err != nil {
 		return 0, err
 	}
 	defer f.Close()
+	if offset > 0 {
+		// Archive members can't seek, so they're skipped through.
+		if seeker, ok := f.(io.Seeker); ok {
+			_, err = seeker.Seek(offset, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, f, offset)
+		}
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
 	limitedReader := io.LimitReader(f, limit)
-	k, err := limitedReader.Read(buf)
+	k, err := io.ReadFull(limitedReader, buf[:min(int64(len(buf)), limit)])
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
 	if err != nil {
 		return 0, err
 	}
 	return k, nil
 }
 
+// Creates node on the file system of it's parent.
 func NewNode(path string, info fs.FileInfo, parent *Node) *Node {
-	return &Node{
+	n := &Node{
 		Path:       path,
 		Info:       info,
 		Children:   nil,
 		Parent:     parent,
 		showHidden: true,
 	}
+	if parent != nil {
+		n.fsys = parent.fsys
+		n.respectIgnore = parent.respectIgnore
+	}
+	return n
+}
+
+// Creates root node on fsys.
+func newRootNode(fsys FS, path string, info fs.FileInfo) *Node {
+	n := NewNode(path, info, nil)
+	n.fsys = fsys
+	return n
 }
 
 func defaultNodeSorting(a, b *Node) int {
-	// dirs first
-	if a.Info.IsDir() != b.Info.IsDir() {
-		if a.Info.IsDir() {
+	return CompareInfos(a.Info, b.Info)
+}
+
+// Orders directories first, then by name ignoring case.
+func CompareInfos(a, b fs.FileInfo) int {
+	if a.IsDir() != b.IsDir() {
+		if a.IsDir() {
 			return -1
 		} else {
 			return 1
 		}
 	}
-	return strings.Compare(strings.ToLower(a.Info.Name()), strings.ToLower(b.Info.Name()))
+	return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
 }
@@ -0,0 +1,78 @@
+package tree
+
+import (
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Local file system.
+type osFS struct{}
+
+func NewOSFS() FS {
+	return osFS{}
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (fsys osFS) Open(name string) (File, error) {
+	return fsys.OpenFile(name, os.O_RDONLY, 0)
+}
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		// Typed nil must not leak into the interface.
+		return nil, err
+	}
+	return f, nil
+}
+func (osFS) Mkdir(name string, perm fs.FileMode) error { return os.Mkdir(name, perm) }
+func (osFS) Rename(oldname, newname string) error      { return os.Rename(oldname, newname) }
+func (osFS) Remove(name string) error                  { return os.Remove(name) }
+func (osFS) Readlink(name string) (string, error)      { return os.Readlink(name) }
+func (osFS) Symlink(oldname, newname string) error     { return os.Symlink(oldname, newname) }
+func (osFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+func (osFS) Watch() (Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &osWatcher{Watcher: w, changes: runFSWatcher(w)}, nil
+}
+
+type osWatcher struct {
+	*fsnotify.Watcher
+	changes <-chan NodeChange
+}
+
+func (w *osWatcher) Changes() <-chan NodeChange {
+	return w.changes
+}
+
+func runFSWatcher(watcher *fsnotify.Watcher) <-chan NodeChange {
+	ch := make(chan NodeChange)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Remove) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) || event.Has(fsnotify.Write) {
+					ch <- NodeChange{Path: event.Name}
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
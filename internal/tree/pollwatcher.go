@@ -0,0 +1,146 @@
+package tree
+
+import (
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultPollInterval = 2 * time.Second
+
+// Watches directories by listing them periodically.
+// Used for file systems, that can't notify about changes by themselves.
+type pollWatcher struct {
+	fsys     FS
+	interval time.Duration
+
+	mu      sync.Mutex
+	dirs    map[string]map[string]pollEntry // directory -> entry name -> state
+	changes chan NodeChange
+	done    chan struct{}
+	close   sync.Once
+}
+
+type pollEntry struct {
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+}
+
+func (e pollEntry) equal(o pollEntry) bool {
+	return e.mode == o.mode && e.size == o.size && e.modTime.Equal(o.modTime)
+}
+
+func newPollWatcher(fsys FS, interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		fsys:     fsys,
+		interval: interval,
+		dirs:     map[string]map[string]pollEntry{},
+		changes:  make(chan NodeChange),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *pollWatcher) Add(name string) error {
+	entries, err := w.list(name)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dirs[name] = entries
+	return nil
+}
+func (w *pollWatcher) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.dirs, name)
+	return nil
+}
+func (w *pollWatcher) Changes() <-chan NodeChange {
+	return w.changes
+}
+func (w *pollWatcher) Close() error {
+	w.close.Do(func() { close(w.done) })
+	return nil
+}
+
+func (w *pollWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+		for _, change := range w.poll() {
+			select {
+			case w.changes <- change:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// Lists all watched directories and reports entries, which differ from the previous listing.
+func (w *pollWatcher) poll() []NodeChange {
+	w.mu.Lock()
+	dirs := slices.Sorted(maps.Keys(w.dirs))
+	w.mu.Unlock()
+
+	changes := []NodeChange{}
+	for _, dir := range dirs {
+		entries, err := w.list(dir)
+
+		w.mu.Lock()
+		prev, watched := w.dirs[dir]
+		if !watched {
+			// Removed while listing.
+			w.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			// Directory itself is gone, so it's not watched anymore.
+			delete(w.dirs, dir)
+			w.mu.Unlock()
+			changes = append(changes, NodeChange{Path: dir})
+			continue
+		}
+		w.dirs[dir] = entries
+		w.mu.Unlock()
+
+		for _, name := range slices.Sorted(maps.Keys(entries)) {
+			if p, ok := prev[name]; !ok || !p.equal(entries[name]) {
+				changes = append(changes, NodeChange{Path: filepath.Join(dir, name)})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(prev)) {
+			if _, ok := entries[name]; !ok {
+				changes = append(changes, NodeChange{Path: filepath.Join(dir, name)})
+			}
+		}
+	}
+	return changes
+}
+func (w *pollWatcher) list(dir string) (map[string]pollEntry, error) {
+	entries, err := w.fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]pollEntry{}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		res[e.Name()] = pollEntry{mode: info.Mode(), size: info.Size(), modTime: info.ModTime()}
+	}
+	return res, nil
+}
@@ -0,0 +1,213 @@
+package tree
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/LeperGnome/bt/pkg/fuzzy"
+)
+
+const (
+	searchUnreadDepthLimit = 5
+	searchCandidatesLimit  = 20_000
+)
+
+type SearchMatch struct {
+	Path      string
+	Node      *Node // nil, if node is not read into the tree yet
+	Score     int
+	Positions []int // indices of matched runes in the node name
+}
+
+type Search struct {
+	Query   string
+	Matches []SearchMatch
+	Current int
+	Finder  *Finder // walks directories, which are not read yet, nil when search is shallow
+
+	candidates []searchCandidate
+	byPath     map[string]int
+	visible    map[string]bool // matches and all their ancestors
+}
+
+type searchCandidate struct {
+	path string
+	node *Node
+}
+
+func (s *Search) CurrentMatch() (SearchMatch, bool) {
+	if len(s.Matches) == 0 {
+		return SearchMatch{}, false
+	}
+	return s.Matches[s.Current], true
+}
+func (s *Search) MatchFor(path string) (SearchMatch, bool) {
+	idx, ok := s.byPath[path]
+	if !ok {
+		return SearchMatch{}, false
+	}
+	return s.Matches[idx], true
+}
+
+// Checks if path is a match or contains matches inside.
+func (s *Search) Visible(path string) bool {
+	return s.visible[path]
+}
+
+// Starts new search over nodes, read into the tree.
+// If deep is set, directories, which are not read yet, are walked in background,
+// their candidates are streamed by returned finder. Otherwise it's nil.
+func (t *Tree) StartSearch(deep bool) *Finder {
+	t.StopSearch()
+	candidates := []searchCandidate{}
+	unread := []walkRoot{}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, ch := range n.Children {
+			if len(candidates) >= searchCandidatesLimit {
+				return
+			}
+			candidates = append(candidates, searchCandidate{path: ch.Path, node: ch})
+			if ch.Children != nil {
+				walk(ch)
+			} else if deep && ch.Info.IsDir() && !ch.IsVirtual() {
+				unread = append(unread, ch.walkRoot())
+			}
+		}
+	}
+	walk(t.Root)
+	t.Search = &Search{candidates: candidates}
+	if len(unread) > 0 && len(candidates) < searchCandidatesLimit {
+		t.Search.Finder = t.startWalker(unread, nil, searchUnreadDepthLimit, searchCandidatesLimit-len(candidates))
+	}
+	t.UpdateSearch("")
+	return t.Search.Finder
+}
+
+// Adds candidates, streamed by a finder of the search. Batches of other finders are ignored.
+func (t *Tree) AddSearchCandidates(batch FindBatch) bool {
+	s := t.Search
+	if s == nil || s.Finder == nil || s.Finder.ID != batch.FinderID {
+		return false
+	}
+	added := len(s.candidates)
+	for _, res := range batch.Results {
+		s.candidates = append(s.candidates, searchCandidate{path: res.Path})
+	}
+	current, hasCurrent := s.CurrentMatch()
+	s.Matches = append(s.Matches, t.matchCandidates(s.candidates[added:])...)
+	t.indexMatches()
+	// New matches don't move the cursor away from the match, user is on.
+	if hasCurrent {
+		s.Current = s.byPath[current.Path]
+	}
+	return true
+}
+
+// Re-scores candidates for new query.
+func (t *Tree) UpdateSearch(query string) {
+	s := t.Search
+	if s == nil {
+		return
+	}
+	s.Query = query
+	s.Current = 0
+	s.Matches = t.matchCandidates(s.candidates)
+	t.indexMatches()
+}
+
+func (t *Tree) matchCandidates(candidates []searchCandidate) []SearchMatch {
+	matches := []SearchMatch{}
+	if t.Search.Query == "" {
+		return matches
+	}
+	for _, c := range candidates {
+		score, positions, ok := fuzzy.Match(t.Search.Query, filepath.Base(c.path))
+		if !ok {
+			continue
+		}
+		matches = append(matches, SearchMatch{Path: c.path, Node: c.node, Score: score, Positions: positions})
+	}
+	return matches
+}
+
+// Sorts matches and remembers their paths.
+func (t *Tree) indexMatches() {
+	s := t.Search
+	// Stable sort keeps equally scored matches in tree order.
+	slices.SortStableFunc(s.Matches, func(a, b SearchMatch) int { return b.Score - a.Score })
+	s.byPath = map[string]int{}
+	s.visible = map[string]bool{}
+	for i, m := range s.Matches {
+		s.byPath[m.Path] = i
+		for p := m.Path; isSubPath(t.Root.Path, p) && !s.visible[p]; p = filepath.Dir(p) {
+			s.visible[p] = true
+		}
+	}
+}
+func (t *Tree) StopSearch() {
+	if t.Search != nil && t.Search.Finder != nil {
+		t.Search.Finder.Stop()
+	}
+	t.Search = nil
+}
+
+// Moves cursor to the next search match, reading it's parents if needed.
+func (t *Tree) SearchNext() error {
+	return t.moveSearch(1)
+}
+func (t *Tree) SearchPrevious() error {
+	return t.moveSearch(-1)
+}
+func (t *Tree) moveSearch(delta int) error {
+	if t.Search == nil || len(t.Search.Matches) == 0 {
+		return nil
+	}
+	l := len(t.Search.Matches)
+	t.Search.Current = ((t.Search.Current+delta)%l + l) % l
+	return t.RevealCurrentMatch()
+}
+func (t *Tree) RevealCurrentMatch() error {
+	if t.Search == nil {
+		return nil
+	}
+	m, ok := t.Search.CurrentMatch()
+	if !ok {
+		return nil
+	}
+	return t.RevealPath(m.Path)
+}
+
+// Selects node with given path, reading all of it's ancestors.
+func (t *Tree) RevealPath(path string) error {
+	if !isSubPath(t.Root.Path, path) {
+		return fmt.Errorf("%s is outside of the tree", path)
+	}
+	rel, err := filepath.Rel(t.Root.Path, path)
+	if err != nil || rel == "." {
+		return err
+	}
+	cur := t.Root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, name := range parts {
+		if cur.Children == nil {
+			if err := cur.readChildren(t.sortingFunc); err != nil {
+				return err
+			}
+			t.watch(cur)
+		}
+		idx := slices.IndexFunc(cur.Children, func(n *Node) bool { return n.Info.Name() == name })
+		if idx < 0 {
+			return fmt.Errorf("%s not found", filepath.Join(cur.Path, name))
+		}
+		if i == len(parts)-1 {
+			cur.selectedChildIdx = idx
+			t.setCurrentDir(cur)
+			return nil
+		}
+		cur = cur.Children[idx]
+	}
+	return nil
+}
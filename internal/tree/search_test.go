@@ -0,0 +1,42 @@
+package tree
+
+func (s *TreeTestSuite) TestSearchLoadedOnly() {
+	s.tree.StartSearch(false)
+	s.tree.UpdateSearch("inner")
+
+	// inner_file is not read yet
+	s.Require().Len(s.tree.Search.Matches, 1)
+	s.Require().Equal("inner_dir", s.tree.Search.Matches[0].Node.Info.Name())
+	s.Require().True(s.tree.Search.Visible(s.tree.Root.Path))
+}
+
+func (s *TreeTestSuite) TestSearchUnreadAndReveal() {
+	finder := s.tree.StartSearch(true)
+	s.tree.UpdateSearch("infl")
+	s.Require().NotNil(finder)
+	for batch := range finder.Batches {
+		s.Require().True(s.tree.AddSearchCandidates(batch))
+	}
+
+	match, ok := s.tree.Search.CurrentMatch()
+	s.Require().True(ok)
+	s.Require().Nil(match.Node)
+	s.Require().Equal([]int{0, 1, 6, 8}, match.Positions)
+
+	err := s.tree.RevealCurrentMatch()
+	s.Require().NoError(err)
+	s.Require().Equal("inner_dir", s.tree.CurrentDir.Info.Name())
+	s.Require().Equal("inner_file", s.tree.GetSelectedChild().Info.Name())
+}
+
+func (s *TreeTestSuite) TestSearchCycle() {
+	s.tree.StartSearch(false)
+	s.tree.UpdateSearch("t")
+	s.Require().Len(s.tree.Search.Matches, 2) // empty_dir, testfile.txt
+
+	s.Require().NoError(s.tree.SearchPrevious())
+	s.Require().Equal(1, s.tree.Search.Current)
+	s.Require().NoError(s.tree.SearchNext())
+	s.Require().Equal(0, s.tree.Search.Current)
+	s.Require().Equal(s.tree.Search.Matches[0].Path, s.tree.GetSelectedChild().Path)
+}
@@ -0,0 +1,242 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	sftpScheme      = "sftp://"
+	sftpDefaultPort = "22"
+	// Remote server's umask is unknown, so the usual one is applied to created files.
+	sftpUmask fs.FileMode = 0o022
+)
+
+// File system on a remote host, accessed over SFTP.
+type sftpFS struct {
+	client   *sftp.Client
+	conn     *ssh.Client // nil, when client isn't connected over ssh
+	location string
+	interval time.Duration
+}
+
+func newSFTPFS(client *sftp.Client, conn *ssh.Client, location string, interval time.Duration) *sftpFS {
+	return &sftpFS{client: client, conn: conn, location: location, interval: interval}
+}
+
+// Picks file system for root, which is either a local path or an sftp:// URL.
+// Returns path of the root on the picked file system.
+func OpenFS(root string) (FS, string, error) {
+	if strings.HasPrefix(root, sftpScheme) {
+		return DialSFTP(root)
+	}
+	return NewOSFS(), root, nil
+}
+
+// Connects to sftp://[user[:password]@]host[:port][/path] using ssh agent and default keys.
+// Host key must be present in ~/.ssh/known_hosts. Empty path and "~" stand for user's home directory.
+func DialSFTP(rawURL string) (FS, string, error) {
+	u, err := parseSFTPURL(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, "", err
+	}
+	hostKeys, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading known hosts: %w", err)
+	}
+	auth, closeAgent := sshAuthMethods(home, u.password)
+	conn, err := ssh.Dial("tcp", u.addr, &ssh.ClientConfig{
+		User:            u.user,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+		Timeout:         10 * time.Second,
+	})
+	// Agent is needed only during authentication.
+	closeAgent()
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, "", err
+	}
+	root := u.path
+	if root == "" || root == "~" || strings.HasPrefix(root, "~/") {
+		wd, err := client.Getwd()
+		if err != nil {
+			client.Close()
+			conn.Close()
+			return nil, "", err
+		}
+		root = path.Join(wd, strings.TrimPrefix(root, "~"))
+	}
+	location := sftpScheme + u.user + "@" + u.addr
+	return newSFTPFS(client, conn, location, defaultPollInterval), root, nil
+}
+
+type sftpURL struct {
+	user     string
+	password string
+	addr     string
+	path     string // relative to home, when starts with "~"
+}
+
+func parseSFTPURL(rawURL string) (sftpURL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return sftpURL{}, err
+	}
+	if u.Scheme != "sftp" || u.Hostname() == "" {
+		return sftpURL{}, fmt.Errorf("%s is not an sftp://[user@]host[:port][/path] URL", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = sftpDefaultPort
+	}
+	res := sftpURL{
+		addr: net.JoinHostPort(u.Hostname(), port),
+		path: u.Path,
+	}
+	if p := strings.TrimPrefix(res.path, "/"); p == "~" || strings.HasPrefix(p, "~/") {
+		res.path = p
+	}
+	if u.User != nil {
+		res.user = u.User.Username()
+		res.password, _ = u.User.Password()
+	}
+	if res.user == "" {
+		cur, err := user.Current()
+		if err != nil {
+			return sftpURL{}, err
+		}
+		res.user = cur.Username
+	}
+	return res, nil
+}
+
+// Agent is tried first, then unencrypted default keys and password from URL.
+// Returned function closes connection to the agent.
+func sshAuthMethods(home, password string) ([]ssh.AuthMethod, func()) {
+	methods := []ssh.AuthMethod{}
+	closeAgent := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closeAgent = func() { conn.Close() }
+		}
+	}
+	signers := []ssh.Signer{}
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		key, err := os.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(key); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	if password != "" {
+		methods = append(methods, ssh.Password(password))
+	}
+	return methods, closeAgent
+}
+
+func (s *sftpFS) Location() string {
+	return s.location
+}
+func (s *sftpFS) Close() error {
+	err := s.client.Close()
+	if s.conn != nil {
+		err = errors.Join(err, s.conn.Close())
+	}
+	return err
+}
+func (s *sftpFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := s.client.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+func (s *sftpFS) Stat(name string) (fs.FileInfo, error)  { return s.client.Stat(name) }
+func (s *sftpFS) Lstat(name string) (fs.FileInfo, error) { return s.client.Lstat(name) }
+func (s *sftpFS) Open(name string) (File, error) {
+	return s.OpenFile(name, os.O_RDONLY, 0)
+}
+func (s *sftpFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	// Servers report failures of exclusive creation differently, so existence is checked beforehand.
+	created := false
+	if flag&os.O_CREATE != 0 {
+		_, err := s.client.Lstat(name)
+		if err == nil && flag&os.O_EXCL != 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+		}
+		created = errors.Is(err, fs.ErrNotExist)
+	}
+	f, err := s.client.OpenFile(name, flag)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		if err := s.client.Chmod(name, perm&^sftpUmask); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+func (s *sftpFS) Mkdir(name string, perm fs.FileMode) error {
+	if _, err := s.client.Lstat(name); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	if err := s.client.Mkdir(name); err != nil {
+		return err
+	}
+	return s.client.Chmod(name, perm&^sftpUmask)
+}
+func (s *sftpFS) Rename(oldname, newname string) error {
+	if _, ok := s.client.HasExtension("posix-rename@openssh.com"); ok {
+		return s.client.PosixRename(oldname, newname)
+	}
+	return s.client.Rename(oldname, newname)
+}
+func (s *sftpFS) Remove(name string) error                  { return s.client.Remove(name) }
+func (s *sftpFS) Readlink(name string) (string, error)      { return s.client.ReadLink(name) }
+func (s *sftpFS) Symlink(oldname, newname string) error     { return s.client.Symlink(oldname, newname) }
+func (s *sftpFS) Chmod(name string, mode fs.FileMode) error { return s.client.Chmod(name, mode) }
+func (s *sftpFS) Chtimes(name string, atime, mtime time.Time) error {
+	return s.client.Chtimes(name, atime, mtime)
+}
+
+// Remote changes can't be subscribed to, so directories are polled.
+func (s *sftpFS) Watch() (Watcher, error) {
+	return newPollWatcher(s, s.interval), nil
+}
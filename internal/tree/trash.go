@@ -0,0 +1,207 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	trashInfoExt        = ".trashinfo"
+	trashInfoTimeFormat = "2006-01-02T15:04:05"
+)
+
+// Trash implements freedesktop.org trash specification.
+// https://specifications.freedesktop.org/trash-spec/latest/
+type Trash struct {
+	fsys      FS
+	homeTrash string
+	uid       int
+}
+
+// TrashedItem describes a file, that was put to trash.
+type TrashedItem struct {
+	OriginalPath string
+	TrashPath    string // path to the file inside "files" directory of a trash
+	InfoPath     string
+}
+
+func NewTrash(fsys FS) (*Trash, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return &Trash{
+		fsys:      fsys,
+		homeTrash: filepath.Join(dataHome, "Trash"),
+		uid:       os.Getuid(),
+	}, nil
+}
+
+// Moves path to the trash directory, located on the same mount as path if possible.
+func (tr *Trash) Put(path string) (TrashedItem, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return TrashedItem{}, &FileOpError{Op: "trash", Path: path, Err: err}
+	}
+	if _, err := tr.fsys.Lstat(path); err != nil {
+		return TrashedItem{}, &FileOpError{Op: "trash", Path: path, Err: unwrapPathErr(err)}
+	}
+	trashDir, topDir := tr.trashDirFor(path)
+
+	infoPath, trashPath, err := tr.reserveInfo(trashDir, topDir, path)
+	if err != nil && trashDir != tr.homeTrash {
+		// Falling back to home trash, if mount's trash is unusable.
+		trashDir, topDir = tr.homeTrash, ""
+		infoPath, trashPath, err = tr.reserveInfo(trashDir, topDir, path)
+	}
+	if err != nil {
+		return TrashedItem{}, &FileOpError{Op: "trash", Path: path, Err: unwrapPathErr(err)}
+	}
+	if err := movePath(tr.fsys, path, tr.fsys, trashPath); err != nil {
+		tr.fsys.Remove(infoPath)
+		return TrashedItem{}, err
+	}
+	return TrashedItem{OriginalPath: path, TrashPath: trashPath, InfoPath: infoPath}, nil
+}
+
+// Moves trashed item back to it's original location.
+func (tr *Trash) Restore(item TrashedItem) error {
+	if _, err := tr.fsys.Lstat(item.OriginalPath); err == nil {
+		return &FileOpError{Op: "restore", Path: item.OriginalPath, Err: fs.ErrExist}
+	}
+	if err := mkdirAll(tr.fsys, filepath.Dir(item.OriginalPath), os.ModePerm); err != nil {
+		return &FileOpError{Op: "restore", Path: item.OriginalPath, Err: unwrapPathErr(err)}
+	}
+	if err := movePath(tr.fsys, item.TrashPath, tr.fsys, item.OriginalPath); err != nil {
+		return err
+	}
+	if err := tr.fsys.Remove(item.InfoPath); err != nil {
+		return &FileOpError{Op: "restore", Path: item.InfoPath, Err: unwrapPathErr(err)}
+	}
+	return nil
+}
+
+// Returns trash directory for path and top directory of it's mount
+// (empty, when home trash is used).
+func (tr *Trash) trashDirFor(path string) (string, string) {
+	homeDev, ok := deviceOf(tr.fsys, tr.homeTrashMount())
+	if !ok {
+		return tr.homeTrash, ""
+	}
+	pathDev, ok := deviceOf(tr.fsys, filepath.Dir(path))
+	if !ok || pathDev == homeDev {
+		return tr.homeTrash, ""
+	}
+	topDir := mountTopDir(tr.fsys, filepath.Dir(path), pathDev)
+	uid := strconv.Itoa(tr.uid)
+
+	// $topdir/.Trash/$uid is used only when .Trash is a real directory with sticky bit set.
+	adminTrash := filepath.Join(topDir, ".Trash")
+	if info, err := tr.fsys.Lstat(adminTrash); err == nil &&
+		info.IsDir() && info.Mode()&fs.ModeSticky != 0 {
+		return filepath.Join(adminTrash, uid), topDir
+	}
+	return filepath.Join(topDir, ".Trash-"+uid), topDir
+}
+
+// Home trash may not exist yet, so looking for it's closest existing ancestor.
+func (tr *Trash) homeTrashMount() string {
+	dir := tr.homeTrash
+	for {
+		if _, err := tr.fsys.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			return dir
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Creates unique .trashinfo file for path and returns it's path
+// along with the path, where trashed file should be moved.
+func (tr *Trash) reserveInfo(trashDir, topDir, path string) (string, string, error) {
+	filesDir := filepath.Join(trashDir, "files")
+	infoDir := filepath.Join(trashDir, "info")
+	for _, dir := range []string{filesDir, infoDir} {
+		if err := mkdirAll(tr.fsys, dir, 0o700); err != nil {
+			return "", "", err
+		}
+	}
+
+	infoPathValue := path
+	if topDir != "" {
+		if rel, err := filepath.Rel(topDir, path); err == nil {
+			infoPathValue = rel
+		}
+	}
+	info := fmt.Sprintf(
+		"[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+		escapeTrashPath(infoPathValue),
+		time.Now().Format(trashInfoTimeFormat),
+	)
+
+	base := filepath.Base(path)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d", base, i)
+		}
+		infoPath := filepath.Join(infoDir, name+trashInfoExt)
+		f, err := tr.fsys.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		trashPath := filepath.Join(filesDir, name)
+		// Info files might have been removed by other tools, leaving files behind.
+		if _, err := tr.fsys.Lstat(trashPath); err == nil {
+			f.Close()
+			tr.fsys.Remove(infoPath)
+			continue
+		}
+		_, err = io.WriteString(f, info)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			tr.fsys.Remove(infoPath)
+			return "", "", err
+		}
+		return infoPath, trashPath, nil
+	}
+}
+
+// Finds the top most directory of the mount, containing dir.
+func mountTopDir(fsys FS, dir string, dev uint64) string {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		if parentDev, ok := deviceOf(fsys, parent); !ok || parentDev != dev {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// Escapes path as URL path, keeping slashes.
+func escapeTrashPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,63 @@
+package tree
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashPutAndRestore(t *testing.T) {
+	dataHome := "/data"
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	fsys := NewMemFS()
+	dir := "/work"
+	require.NoError(t, fsys.Mkdir(dir, 0o755))
+	createTestFileTree(t, fsys, dir, testnode{
+		name: "dir with spaces",
+		children: []testnode{
+			{name: "file.txt", isFile: true, content: []byte("content")},
+		},
+	})
+	src := path.Join(dir, "dir with spaces")
+
+	trash, err := NewTrash(fsys)
+	require.NoError(t, err)
+
+	item, err := trash.Put(src)
+	require.NoError(t, err)
+	require.Equal(t, path.Join(dataHome, "Trash", "files", "dir with spaces"), item.TrashPath)
+
+	requireNotExists(t, fsys, src)
+
+	info := readTestFile(t, fsys, item.InfoPath)
+	require.True(t, strings.HasPrefix(info, "[Trash Info]\n"))
+	require.Contains(t, info, "Path="+strings.ReplaceAll(src, " ", "%20")+"\n")
+	require.Contains(t, info, "DeletionDate=")
+
+	require.NoError(t, trash.Restore(item))
+	require.Equal(t, "content", readTestFile(t, fsys, path.Join(src, "file.txt")))
+
+	requireNotExists(t, fsys, item.InfoPath)
+}
+
+func TestTrashNameConflict(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/data")
+	fsys := NewMemFS()
+	trash, err := NewTrash(fsys)
+	require.NoError(t, err)
+
+	dir := "/"
+	items := []TrashedItem{}
+	for range 2 {
+		createTestFileTree(t, fsys, dir, testnode{name: "file.txt", isFile: true})
+		item, err := trash.Put(path.Join(dir, "file.txt"))
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	require.NotEqual(t, items[0].TrashPath, items[1].TrashPath)
+	require.Equal(t, "file.txt.2", path.Base(items[1].TrashPath))
+	require.Equal(t, "file.txt.2.trashinfo", path.Base(items[1].InfoPath))
+}
@@ -1,24 +1,30 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"os"
-	"os/exec"
 	"path/filepath"
 	"slices"
 	"strings"
-
-	"github.com/fsnotify/fsnotify"
 )
 
+var errOtherRootMark = errors.New("marked on another root, it can only be copied or moved here")
+
 type Tree struct {
 	Root       *Node
 	CurrentDir *Node
 	Marked     []*Node
+	Search     *Search    // nil, when not searching
+	Git        *GitStatus // nil, when root isn't in a git repository
 
-	sortingFunc NodeSortingFunc
-	watcher     *fsnotify.Watcher
+	sortingFunc   NodeSortingFunc
+	respectIgnore bool
+	fsys          FS
+	watcher       Watcher
+	trash         *Trash
+	journal       *Journal
 }
 
 func (t *Tree) GetSelectedChild() *Node {
@@ -27,14 +33,32 @@ func (t *Tree) GetSelectedChild() *Node {
 	}
 	return nil
 }
+
+// Returns up to n children after the selected one and n before it, nearest first.
+func (t *Tree) GetSelectedChildNeighbours(n int) []*Node {
+	children := t.CurrentDir.Children
+	idx := t.CurrentDir.selectedChildIdx
+	res := []*Node{}
+	for i := 1; i <= n; i++ {
+		if idx+i < len(children) {
+			res = append(res, children[idx+i])
+		}
+		if idx-i >= 0 && idx-i < len(children) {
+			res = append(res, children[idx-i])
+		}
+	}
+	return res
+}
 func (t *Tree) ToggleHiddenInCurrentDirectory() error {
 	t.CurrentDir.showHidden = !t.CurrentDir.showHidden
 	return t.CurrentDir.readChildren(defaultNodeSorting)
 }
-func (t *Tree) RemoveNodeFromMarkByPath(path string) {
+
+// Unmarks node at path of src's file system. Marks could be taken here from another root.
+func (t *Tree) RemoveNodeFromMarkByPath(src *Tree, path string) {
 	t.Marked = slices.DeleteFunc(
 		t.Marked,
-		func(n *Node) bool { return n.Path == path },
+		func(n *Node) bool { return n.fsys == src.fsys && n.Path == path },
 	)
 }
 func (t *Tree) RefreshNodeParentByPath(path string) error {
@@ -61,30 +85,47 @@ func (t *Tree) RenameMarked(newName string) error {
 		return nil
 	}
 	marked := t.Marked[0]
+	if marked.IsVirtual() {
+		return errReadOnlyArchive
+	}
+	if marked.fsys != t.fsys {
+		return &FileOpError{Op: "rename", Path: marked.Path, Err: errOtherRootMark}
+	}
 
 	if newName == "" {
 		return fmt.Errorf("new name must not be empty")
 	}
 	targetPath := filepath.Join(marked.Parent.Path, newName)
 	// NOTE: probably not the best way to check if file exists
-	if _, err := os.Stat(targetPath); err == nil {
+	if _, err := t.fsys.Stat(targetPath); err == nil {
 		return fmt.Errorf("file %s already exists", newName)
-	} else if !os.IsNotExist(err) {
+	} else if !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
-	err := os.Rename(marked.Path, targetPath)
+	err := t.fsys.Rename(marked.Path, targetPath)
 	if err != nil {
 		return err
 	}
+	t.journal.record(opRename, []*journalItem{{src: marked.Path, dst: targetPath}})
 	t.Marked = nil
 	return nil
 }
 func (t *Tree) CreateFileInCurrent(name string) error {
-	_, err := os.Create(filepath.Join(t.CurrentDir.Path, name))
-	return err
+	return t.createInCurrent(name, false)
 }
 func (t *Tree) CreateDirectoryInCurrent(name string) error {
-	return os.Mkdir(filepath.Join(t.CurrentDir.Path, name), os.ModePerm)
+	return t.createInCurrent(name, true)
+}
+func (t *Tree) createInCurrent(name string, isDir bool) error {
+	if t.CurrentDir.archive != nil {
+		return errReadOnlyArchive
+	}
+	path := filepath.Join(t.CurrentDir.Path, name)
+	if err := createPath(t.fsys, path, isDir); err != nil {
+		return err
+	}
+	t.journal.record(opCreate, []*journalItem{{dst: path, isDir: isDir}})
+	return nil
 }
 func (t *Tree) SelectNextChild() {
 	if t.CurrentDir.selectedChildIdx < len(t.CurrentDir.Children)-1 {
@@ -102,27 +143,99 @@ func (t *Tree) SetSelectedChildAsCurrent() error {
 		return nil
 	}
 	if !selectedChild.Info.IsDir() {
-		return nil
+		if !IsArchiveName(selectedChild.Info.Name()) || !selectedChild.Info.Mode().IsRegular() || selectedChild.IsVirtual() {
+			return nil
+		}
+		if err := selectedChild.openArchive(); err != nil {
+			return err
+		}
 	}
 	if selectedChild.Children == nil {
 		err := selectedChild.readChildren(t.sortingFunc)
 		if err != nil {
 			return err
 		}
-		t.watcher.Add(selectedChild.Path)
+		t.watch(selectedChild)
 	}
-	t.CurrentDir = selectedChild
+	t.setCurrentDir(selectedChild)
 	return nil
 }
-func (t *Tree) SetParentAsCurrent() {
-	if t.CurrentDir.Parent != nil {
-		currentName := t.CurrentDir.Info.Name()
-		// setting parent current to match the directory, that we're leaving
-		newParentIdx := slices.IndexFunc(t.CurrentDir.Parent.Children, func(n *Node) bool { return n.Info.Name() == currentName })
-		t.CurrentDir.Parent.selectedChildIdx = newParentIdx
+func (t *Tree) SetParentAsCurrent() error {
+	if t.CurrentDir.Parent == nil {
+		if err := t.ExtendRootUp(); err != nil {
+			return err
+		}
+	}
+	currentName := t.CurrentDir.Info.Name()
+	// setting parent current to match the directory, that we're leaving
+	newParentIdx := slices.IndexFunc(t.CurrentDir.Parent.Children, func(n *Node) bool { return n.Info.Name() == currentName })
+	t.CurrentDir.Parent.selectedChildIdx = newParentIdx
 
-		t.CurrentDir = t.CurrentDir.Parent
+	t.setCurrentDir(t.CurrentDir.Parent)
+	return nil
+}
+
+// Changes current directory, releasing file of an archive, that is left.
+func (t *Tree) setCurrentDir(n *Node) {
+	if a := t.CurrentDir.archive; a != nil && n.archive != a {
+		a.Close()
 	}
+	t.CurrentDir = n
+}
+
+// Makes parent directory of the root a new root, keeping existing tree as it's child.
+func (t *Tree) ExtendRootUp() error {
+	parentPath := filepath.Dir(t.Root.Path)
+	if parentPath == t.Root.Path {
+		return fmt.Errorf("%s has no parent", t.Root.Path)
+	}
+	parentInfo, err := t.fsys.Lstat(parentPath)
+	if err != nil {
+		return err
+	}
+	newRoot := newRootNode(t.fsys, parentPath, parentInfo)
+	newRoot.respectIgnore = t.respectIgnore
+	// Existing children are kept by readChildren, so current root is grafted in place.
+	newRoot.Children = []*Node{t.Root}
+	if err := newRoot.readChildren(t.sortingFunc); err != nil {
+		return err
+	}
+	rootIdx := slices.Index(newRoot.Children, t.Root)
+	if rootIdx < 0 {
+		return fmt.Errorf("%s not found in %s", t.Root.Info.Name(), parentPath)
+	}
+	newRoot.selectedChildIdx = rootIdx
+
+	t.watcher.Add(parentPath)
+	t.Root.Parent = newRoot
+	t.Root = newRoot
+	return nil
+}
+
+// Makes current directory a new root, dropping everything outside of it.
+func (t *Tree) SetCurrentAsRoot() {
+	newRoot := t.CurrentDir
+	// Root is expected to be on disk.
+	if newRoot == t.Root || newRoot.IsVirtual() {
+		return
+	}
+	// Directories outside of the new root are not rendered anymore, so they're not watched.
+	var unwatch func(n *Node)
+	unwatch = func(n *Node) {
+		if n == newRoot || n.Children == nil {
+			return
+		}
+		t.unwatch(n)
+		for _, ch := range n.Children {
+			unwatch(ch)
+		}
+	}
+	unwatch(t.Root)
+
+	t.Marked = slices.DeleteFunc(t.Marked, func(n *Node) bool { return !isSubPath(newRoot.Path, n.Path) })
+	t.StopSearch()
+	newRoot.Parent = nil
+	t.Root = newRoot
 }
 func (t *Tree) ToggleMarkSelectedChild() bool {
 	if selected := t.GetSelectedChild(); selected != nil {
@@ -147,61 +260,116 @@ func (t *Tree) MarkSelectedChild() bool {
 func (t *Tree) DropMark() {
 	t.Marked = nil
 }
+
+// Moves marked nodes to trash.
 func (t *Tree) DeleteMarked() error {
-	if t.Marked == nil {
-		return nil
+	if t.trash == nil {
+		return fmt.Errorf("trash is not available, use permanent delete instead")
 	}
-	for _, marked := range t.Marked {
-		cmd := exec.Command("rm", "-r", marked.Path)
-		err := cmd.Run()
+	return t.applyToMarked(opDelete, func(marked *Node) (*journalItem, error) {
+		if marked.IsVirtual() {
+			return nil, &FileOpError{Op: "delete", Path: marked.Path, Err: errReadOnlyArchive}
+		}
+		// Trash is of the tree's own file system.
+		if marked.fsys != t.fsys {
+			return nil, &FileOpError{Op: "delete", Path: marked.Path, Err: errOtherRootMark}
+		}
+		trashed, err := t.trash.Put(marked.Path)
 		if err != nil {
-			return err // todo: this is not the same error...?
+			return nil, err
+		}
+		return &journalItem{src: marked.Path, dst: trashed.TrashPath, trashed: trashed}, nil
+	})
+}
+
+// Removes marked nodes without a way to restore them.
+func (t *Tree) DeleteMarkedPermanently() error {
+	deleted := false
+	err := t.applyToMarked(opDelete, func(marked *Node) (*journalItem, error) {
+		if marked.IsVirtual() {
+			return nil, &FileOpError{Op: "delete", Path: marked.Path, Err: errReadOnlyArchive}
+		}
+		if err := removePath(marked.fsys, marked.Path); err != nil {
+			return nil, err
 		}
+		deleted = true
+		return nil, nil
+	})
+	if deleted {
+		t.journal.dropRedo()
 	}
-	t.Marked = nil
-	return nil
+	return err
 }
+
+// Copies marked nodes into current directory. Archive members are extracted.
 func (t *Tree) CopyMarkedToCurrentDir() error {
-	if t.Marked == nil {
-		return nil
+	if t.CurrentDir.archive != nil {
+		return errReadOnlyArchive
 	}
 	targetDir := t.CurrentDir.Path
-	for _, marked := range t.Marked {
-		targetFileName, err := generateNewFileName(marked.Info.Name(), targetDir)
+	return t.applyToMarked(opCopy, func(marked *Node) (*journalItem, error) {
+		targetFileName, err := generateNewFileName(t.fsys, marked.Info.Name(), targetDir)
 		if err != nil {
-			return err
+			return nil, &FileOpError{Op: "copy", Path: targetDir, Err: unwrapPathErr(err)}
 		}
 		targetPath := filepath.Join(targetDir, targetFileName)
-
-		cmd := exec.Command("cp", "-r", marked.Path, targetPath)
-		err = cmd.Run()
-		if err != nil {
-			return err // todo: this is not the same error...?
+		if marked.IsVirtual() {
+			if err := extractNode(t.fsys, marked, targetPath); err != nil {
+				return nil, err
+			}
+			return &journalItem{src: marked.Path, dst: targetPath, extracted: marked}, nil
 		}
-	}
-	t.Marked = nil
-	return nil
+		if err := copyPath(marked.fsys, marked.Path, t.fsys, targetPath); err != nil {
+			return nil, err
+		}
+		return &journalItem{src: marked.Path, srcFS: marked.fsys, dst: targetPath}, nil
+	})
 }
 func (t *Tree) MoveMarkedToCurrentDir() error {
-	if t.Marked == nil {
-		return nil
+	if t.CurrentDir.archive != nil {
+		return errReadOnlyArchive
 	}
 	targetDir := t.CurrentDir.Path
-	for _, marked := range t.Marked {
-		targetFileName, err := generateNewFileName(marked.Info.Name(), targetDir)
+	return t.applyToMarked(opMove, func(marked *Node) (*journalItem, error) {
+		if marked.IsVirtual() {
+			return nil, &FileOpError{Op: "move", Path: marked.Path, Err: errReadOnlyArchive}
+		}
+		targetFileName, err := generateNewFileName(t.fsys, marked.Info.Name(), targetDir)
 		if err != nil {
-			return err
+			return nil, &FileOpError{Op: "move", Path: targetDir, Err: unwrapPathErr(err)}
 		}
 		targetPath := filepath.Join(targetDir, targetFileName)
+		if err := movePath(marked.fsys, marked.Path, t.fsys, targetPath); err != nil {
+			return nil, err
+		}
+		return &journalItem{src: marked.Path, srcFS: marked.fsys, dst: targetPath}, nil
+	})
+}
 
-		cmd := exec.Command("mv", "-n", marked.Path, targetPath)
-		err = cmd.Run()
+// Applies op to every marked node and records successful ones to journal.
+// Nodes, for which op failed, stay marked.
+func (t *Tree) applyToMarked(jop journalOp, op func(marked *Node) (*journalItem, error)) error {
+	if t.Marked == nil {
+		return nil
+	}
+	errs := []error{}
+	failed := []*Node{}
+	items := []*journalItem{}
+	for _, marked := range t.Marked {
+		item, err := op(marked)
 		if err != nil {
-			return err // todo: this is not the same error...?
+			errs = append(errs, err)
+			failed = append(failed, marked)
+		} else if item != nil {
+			items = append(items, item)
 		}
 	}
+	t.journal.record(jop, items)
 	t.Marked = nil
-	return nil
+	if len(failed) > 0 {
+		t.Marked = failed
+	}
+	return collectOpErrors(errs)
 }
 func (t *Tree) CollapseOrExpandSelected() error {
 	selectedChild := t.GetSelectedChild()
@@ -209,25 +377,51 @@ func (t *Tree) CollapseOrExpandSelected() error {
 		return nil
 	}
 	if selectedChild.Children != nil {
+		t.unwatch(selectedChild)
 		selectedChild.orphanChildren()
-		t.watcher.Remove(selectedChild.Path)
 	} else {
 		err := selectedChild.readChildren(t.sortingFunc)
 		if err != nil {
 			return err
 		}
-		t.watcher.Add(selectedChild.Path)
+		t.watch(selectedChild)
 	}
 	return nil
 }
 
-func InitTree(dir string, sortingFunc NodeSortingFunc) (*Tree, <-chan NodeChange, error) {
+// Location of the tree's file system, empty for local one.
+func (t *Tree) Location() string {
+	return fsLocation(t.fsys)
+}
+
+// Archives are read once, so only directories on disk are watched.
+func (t *Tree) watch(n *Node) {
+	if n.archive == nil {
+		t.watcher.Add(n.Path)
+	}
+}
+func (t *Tree) unwatch(n *Node) {
+	if n.archive == nil {
+		t.watcher.Remove(n.Path)
+	}
+}
+
+// Stops watching and closes connection of a remote file system.
+func (t *Tree) Close() error {
+	err := t.watcher.Close()
+	if c, ok := t.fsys.(io.Closer); ok {
+		err = errors.Join(err, c.Close())
+	}
+	return err
+}
+
+func InitTree(fsys FS, dir string, sortingFunc NodeSortingFunc) (*Tree, <-chan NodeChange, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	rootInfo, err := os.Lstat(absDir)
+	rootInfo, err := fsys.Lstat(absDir)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -238,7 +432,7 @@ func InitTree(dir string, sortingFunc NodeSortingFunc) (*Tree, <-chan NodeChange
 		sortingFunc = defaultNodeSorting
 	}
 
-	root := NewNode(absDir, rootInfo, nil)
+	root := newRootNode(fsys, absDir, rootInfo)
 
 	err = root.readChildren(sortingFunc)
 	if err != nil {
@@ -248,29 +442,39 @@ func InitTree(dir string, sortingFunc NodeSortingFunc) (*Tree, <-chan NodeChange
 		return nil, nil, fmt.Errorf("Can't initialize on empty directory '%s'", absDir)
 	}
 
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := fsys.Watch()
 	if err != nil {
 		return nil, nil, err
 	}
-	changeChan := runFSWatcher(watcher)
 	err = watcher.Add(root.Path)
 	if err != nil {
+		watcher.Close()
 		return nil, nil, err
 	}
 
+	// Without trash, only permanent deletion is possible.
+	// Trash is local, so remote files can't be put there.
+	var trash *Trash
+	if fsLocation(fsys) == "" {
+		trash, _ = NewTrash(fsys)
+	}
+
 	tree := &Tree{
 		Root:        root,
 		CurrentDir:  root,
 		sortingFunc: sortingFunc,
+		fsys:        fsys,
 		watcher:     watcher,
+		trash:       trash,
+		journal:     &Journal{fsys: fsys},
 	}
-	return tree, changeChan, nil
+	return tree, watcher.Changes(), nil
 }
 
 // Checks if fname already exists in targetDir.
 // Adds "copy_" prefix (multiple times), until new file name becomes unique in derecotry.
-func generateNewFileName(fname, targetDir string) (string, error) {
-	currentDirContent, err := os.ReadDir(targetDir)
+func generateNewFileName(fsys FS, fname, targetDir string) (string, error) {
+	currentDirContent, err := fsys.ReadDir(targetDir)
 	if err != nil {
 		return "", err
 	}
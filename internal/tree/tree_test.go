@@ -1,8 +1,13 @@
 package tree
 
 import (
+	"context"
+	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,17 +16,21 @@ import (
 
 type TreeTestSuite struct {
 	suite.Suite
+	fsys FS
 	tree *Tree
 }
 
 func (s *TreeTestSuite) SetupTest() {
-	// Create tmp tmpdir files and dirs
-	tmpdir := s.T().TempDir()
+	s.T().Setenv("XDG_DATA_HOME", "/data")
+	s.fsys = NewMemFS()
+
+	// Create files and dirs in memory
 	rootDirName := "rootdir"
-	rootPath := path.Join(tmpdir, rootDirName)
+	rootPath := path.Join("/", rootDirName)
 	createTestFileTree(
 		s.T(),
-		tmpdir,
+		s.fsys,
+		"/",
 		testnode{
 			name:   rootDirName,
 			isFile: false,
@@ -52,7 +61,7 @@ func (s *TreeTestSuite) SetupTest() {
 	)
 
 	// Init Tree with this dir
-	tree, _, err := InitTree(rootPath, defaultNodeSorting)
+	tree, _, err := InitTree(s.fsys, rootPath, defaultNodeSorting)
 	s.Require().NoError(err)
 	s.tree = tree
 }
@@ -61,6 +70,28 @@ func (s *TreeTestSuite) TestInit() {
 	s.Require().NotNil(s.tree.Root)
 	s.Require().Len(s.tree.Root.Children, 3)
 }
+func (s *TreeTestSuite) TestDiskUsage() {
+	size, complete := s.tree.Root.DiskUsage(context.Background(), 100)
+	s.Require().True(complete)
+	s.Require().Equal(int64(len("inner file content")+len("some content")), size)
+
+	_, complete = s.tree.Root.DiskUsage(context.Background(), 2)
+	s.Require().False(complete)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, complete = s.tree.Root.DiskUsage(ctx, 100)
+	s.Require().False(complete, "cancelled walk is incomplete")
+}
+func (s *TreeTestSuite) TestSelectedChildNeighbours() {
+	s.tree.SelectNextChild()
+	names := []string{}
+	for _, n := range s.tree.GetSelectedChildNeighbours(5) {
+		names = append(names, n.Info.Name())
+	}
+	s.Require().Equal([]string{"testfile.txt", "empty_dir"}, names)
+	s.Require().Len(s.tree.GetSelectedChildNeighbours(0), 0)
+}
 func (s *TreeTestSuite) TestMultiMarkSelected() {
 	ok := s.tree.MarkSelectedChild()
 	s.Require().True(ok)
@@ -121,11 +152,85 @@ func (s *TreeTestSuite) TestMultiSelectCopy() {
 	s.Require().Len(s.tree.CurrentDir.Children, 2)
 	s.Require().Len(s.tree.Marked, 0)
 }
+func (s *TreeTestSuite) TestDeleteMarkedToTrash() {
+	s.tree.CurrentDir.SelectLast()
+	ok := s.tree.MarkSelectedChild()
+	s.Require().True(ok)
+
+	err := s.tree.DeleteMarked()
+	s.Require().NoError(err)
+	s.Require().Len(s.tree.Marked, 0)
+
+	s.tree.CurrentDir.readChildren(defaultNodeSorting)
+	s.Require().Len(s.tree.CurrentDir.Children, 2)
+
+	trashed, err := s.fsys.ReadDir(path.Join(os.Getenv("XDG_DATA_HOME"), "Trash", "files"))
+	s.Require().NoError(err)
+	s.Require().Len(trashed, 1)
+	s.Require().Equal("testfile.txt", trashed[0].Name())
+}
+func (s *TreeTestSuite) TestGoAboveRoot() {
+	oldRoot := s.tree.Root
+	oldRoot.SelectLast()
+
+	err := s.tree.SetParentAsCurrent()
+	s.Require().NoError(err)
+
+	s.Require().Equal(path.Dir(oldRoot.Path), s.tree.Root.Path)
+	s.Require().Same(s.tree.Root, s.tree.CurrentDir)
+	s.Require().Same(oldRoot, s.tree.GetSelectedChild())
+	s.Require().Same(s.tree.Root, oldRoot.Parent)
+	// Grafted subtree keeps it's state.
+	s.Require().Len(oldRoot.Children, 3)
+	s.Require().Equal("testfile.txt", oldRoot.Children[oldRoot.selectedChildIdx].Info.Name())
+}
+
+func (s *TreeTestSuite) TestSetCurrentAsRoot() {
+	s.tree.SelectNextChild()
+	s.Require().NoError(s.tree.SetSelectedChildAsCurrent())
+	s.Require().Equal("inner_dir", s.tree.CurrentDir.Info.Name())
+
+	s.tree.SetCurrentAsRoot()
+	s.Require().Same(s.tree.CurrentDir, s.tree.Root)
+	s.Require().Nil(s.tree.Root.Parent)
+
+	// Going back up re-reads the parent.
+	s.Require().NoError(s.tree.SetParentAsCurrent())
+	s.Require().Equal("rootdir", s.tree.Root.Info.Name())
+	s.Require().Equal("inner_dir", s.tree.GetSelectedChild().Info.Name())
+}
 
 func TestTreeTestSuite(t *testing.T) {
 	suite.Run(t, new(TreeTestSuite))
 }
 
+// Marks, carried from another root, must not be trashed by path on the tree's own file system.
+func TestDeleteMarkedOfOtherRoot(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir := t.TempDir()
+	writeTestFile(t, NewOSFS(), filepath.Join(dir, "file.txt"), []byte("local"))
+	local, _, err := InitTree(NewOSFS(), dir, nil)
+	require.NoError(t, err)
+
+	memfs := NewMemFS()
+	// Same path as the local root.
+	p := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(dir, "/"), "/") {
+		p = path.Join(p, name)
+		require.NoError(t, memfs.Mkdir(p, os.ModePerm))
+	}
+	writeTestFile(t, memfs, filepath.Join(dir, "file.txt"), []byte("mem"))
+	mem, _, err := InitTree(memfs, dir, nil)
+	require.NoError(t, err)
+
+	require.True(t, mem.MarkSelectedChild())
+	local.Marked = append(local.Marked, mem.Marked...)
+	require.ErrorIs(t, local.DeleteMarked(), errOtherRootMark)
+	require.Len(t, local.Marked, 1, "refused mark is kept")
+	require.Equal(t, "local", readTestFile(t, NewOSFS(), filepath.Join(dir, "file.txt")))
+	require.Equal(t, "mem", readTestFile(t, memfs, filepath.Join(dir, "file.txt")))
+}
+
 type testnode struct {
 	isFile   bool
 	name     string
@@ -133,23 +238,50 @@ type testnode struct {
 	children []testnode
 }
 
-func createTestFileTree(tb testing.TB, dir string, node testnode) {
+func createTestFileTree(tb testing.TB, fsys FS, dir string, node testnode) {
 	tb.Helper()
 	if node.isFile {
-		f, err := os.Create(path.Join(dir, node.name))
-		require.NoError(tb, err)
-		defer f.Close()
-
-		_, err = f.Write(node.content)
-		require.NoError(tb, err)
-
+		writeTestFile(tb, fsys, path.Join(dir, node.name), node.content)
 	} else {
 		newDir := path.Join(dir, node.name)
-		err := os.Mkdir(newDir, os.ModePerm)
+		err := fsys.Mkdir(newDir, os.ModePerm)
 		require.NoError(tb, err)
 
 		for _, child := range node.children {
-			createTestFileTree(tb, newDir, child)
+			createTestFileTree(tb, fsys, newDir, child)
 		}
 	}
 }
+
+func writeTestFile(tb testing.TB, fsys FS, name string, content []byte) {
+	tb.Helper()
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	require.NoError(tb, err)
+	defer f.Close()
+
+	_, err = f.Write(content)
+	require.NoError(tb, err)
+}
+
+func readTestFile(tb testing.TB, fsys FS, name string) string {
+	tb.Helper()
+	f, err := fsys.Open(name)
+	require.NoError(tb, err)
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	require.NoError(tb, err)
+	return string(content)
+}
+
+func requireExists(tb testing.TB, fsys FS, name string) {
+	tb.Helper()
+	_, err := fsys.Lstat(name)
+	require.NoError(tb, err)
+}
+
+func requireNotExists(tb testing.TB, fsys FS, name string) {
+	tb.Helper()
+	_, err := fsys.Lstat(name)
+	require.ErrorIs(tb, err, fs.ErrNotExist)
+}
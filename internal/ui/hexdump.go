@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	hexOffsetWidth = len("00000000: ")
+	maxBytesPerRow = 32
+)
+
+type magic struct {
+	offset int
+	prefix string
+	desc   string
+}
+
+// Checked in order, so more specific signatures go first.
+var magics = []magic{
+	{0, "\x7fELF\x01", "ELF 32-bit"},
+	{0, "\x7fELF\x02", "ELF 64-bit"},
+	{0, "\x7fELF", "ELF"},
+	{0, "\xcf\xfa\xed\xfe", "Mach-O 64-bit"},
+	{0, "\xce\xfa\xed\xfe", "Mach-O 32-bit"},
+	{0, "\xca\xfe\xba\xbe", "Mach-O universal binary or Java class"},
+	{0, "MZ", "PE / DOS executable"},
+	{0, "\x00asm", "WebAssembly binary"},
+	{0, "\x1f\x8b", "gzip compressed data"},
+	{0, "BZh", "bzip2 compressed data"},
+	{0, "\xfd7zXZ\x00", "xz compressed data"},
+	{0, "\x28\xb5\x2f\xfd", "zstd compressed data"},
+	{0, "PK\x03\x04", "Zip archive"},
+	{0, "PK\x05\x06", "Zip archive (empty)"},
+	{0, "7z\xbc\xaf\x27\x1c", "7-zip archive"},
+	{0, "Rar!\x1a\x07", "RAR archive"},
+	{257, "ustar", "POSIX tar archive"},
+	{0, "%PDF-", "PDF document"},
+	{0, "SQLite format 3\x00", "SQLite 3 database"},
+	{0, "\x89PNG\r\n\x1a\n", "PNG image"},
+	{0, "\xff\xd8\xff", "JPEG image"},
+	{0, "GIF87a", "GIF image"},
+	{0, "GIF89a", "GIF image"},
+	{8, "WEBP", "WebP image"},
+	{8, "WAVE", "WAVE audio"},
+	{8, "AVI ", "AVI video"},
+	{0, "OggS", "Ogg data"},
+	{0, "ID3", "MP3 audio"},
+	{0, "fLaC", "FLAC audio"},
+	{4, "ftyp", "ISO media (MP4 / MOV)"},
+	{0, "wOFF", "WOFF font"},
+	{0, "wOF2", "WOFF2 font"},
+	{0, "\x00\x01\x00\x00\x00", "TrueType font"},
+	{0, "OTTO", "OpenType font"},
+	{0, "\xed\xab\xee\xdb", "RPM package"},
+	{0, "!<arch>\n", "ar archive (deb / static library)"},
+}
+
+// Detects file type by magic bytes, like file(1) does.
+func detectFileType(data []byte) string {
+	for _, m := range magics {
+		if len(data) >= m.offset && bytes.HasPrefix(data[m.offset:], []byte(m.prefix)) {
+			return m.desc
+		}
+	}
+	return "data"
+}
+
+// Returns how many bytes fit into a row of xxd-style dump of given width.
+func hexBytesPerRow(width int) int {
+	// Offset, 2 bytes per group of 5 symbols (4 hex digits and space), extra space and 1 symbol per byte.
+	n := int(float64(width-hexOffsetWidth-1) / 3.5)
+	n -= n % 2
+	return max(min(n, maxBytesPerRow), 2)
+}
+
+// Renders xxd-style hex dump of data, located at base offset in the file, fitting into width symbols.
+func hexDump(data []byte, base int64, width, rows int) []string {
+	perRow := hexBytesPerRow(width)
+	lines := []string{}
+	for offset := 0; offset < len(data) && len(lines) < rows; offset += perRow {
+		chunk := data[offset:min(offset+perRow, len(data))]
+		line := strings.Builder{}
+		fmt.Fprintf(&line, "%08x: ", base+int64(offset))
+		for i := range perRow {
+			if i < len(chunk) {
+				fmt.Fprintf(&line, "%02x", chunk[i])
+			} else {
+				line.WriteString("  ")
+			}
+			if i%2 == 1 {
+				line.WriteByte(' ')
+			}
+		}
+		line.WriteByte(' ')
+		for _, b := range chunk {
+			if b >= 0x20 && b < 0x7f {
+				line.WriteByte(b)
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
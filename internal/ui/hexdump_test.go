@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHexDump(t *testing.T) {
+	data := []byte("\x7fELF\x02\x01\x01\x00hello, world!\n\x00\xff")
+
+	lines := hexDump(data, 0, 67, 10)
+	require.Equal(t, []string{
+		"00000000: 7f45 4c46 0201 0100 6865 6c6c 6f2c 2077  .ELF....hello, w",
+		"00000010: 6f72 6c64 210a 00ff                      orld!...",
+	}, lines)
+	require.Len(t, hexDump(data, 0, 67, 1), 1, "rows are limited")
+
+	for _, width := range []int{20, 40, 67, 100, 200} {
+		for _, line := range hexDump(data, 0, width, 10) {
+			if hexBytesPerRow(width) > 2 {
+				require.LessOrEqual(t, len(line), width, "width %d", width)
+			}
+		}
+	}
+	require.Equal(t, maxBytesPerRow, hexBytesPerRow(1000))
+	require.Equal(t, 2, hexBytesPerRow(5))
+}
+
+func TestDetectFileType(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar\x0000")
+
+	cases := map[string]string{
+		"\x7fELF\x02\x01\x01":       "ELF 64-bit",
+		"\x1f\x8b\x08\x00":          "gzip compressed data",
+		"PK\x03\x04\x14\x00":        "Zip archive",
+		"%PDF-1.7\n":                "PDF document",
+		"SQLite format 3\x00\x10":   "SQLite 3 database",
+		string(tar):                 "POSIX tar archive",
+		"RIFF\x00\x00\x00\x00WEBPV": "WebP image",
+		"\x00\x00\x00\x00":          "data",
+		"":                          "data",
+	}
+	for data, want := range cases {
+		require.Equal(t, want, detectFileType([]byte(data)), "%q", data[:min(len(data), 8)])
+	}
+}
+
+func TestTrimIncompleteRune(t *testing.T) {
+	text := []byte("hi, мир")
+	require.Equal(t, text, trimIncompleteRune(text))
+	require.Equal(t, []byte("hi, ми"), trimIncompleteRune(text[:len(text)-1]))
+	require.True(t, strings.HasPrefix(string(text), string(trimIncompleteRune(text[:5]))))
+}
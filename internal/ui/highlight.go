@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Picks lexer by file name, then by shebang or content. Returns nil for plain text.
+func detectLexer(name string, content []byte) chroma.Lexer {
+	if l := lexers.Match(name); l != nil {
+		return l
+	}
+	if bytes.HasPrefix(content, []byte("#!")) {
+		if l := shebangLexer(content); l != nil {
+			return l
+		}
+	}
+	if l := lexers.Analyse(string(content)); l != nil {
+		return l
+	}
+	return nil
+}
+
+// Finds lexer by interpreter in the shebang line, e.g. "#!/usr/bin/env python3".
+func shebangLexer(content []byte) chroma.Lexer {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	fields := strings.Fields(strings.TrimPrefix(string(line), "#!"))
+	if len(fields) == 0 {
+		return nil
+	}
+	interp := fields[0]
+	if strings.HasSuffix(interp, "/env") && len(fields) > 1 {
+		interp = fields[1]
+	}
+	interp = interp[strings.LastIndex(interp, "/")+1:]
+	interp = strings.TrimRight(interp, "0123456789.")
+	switch interp {
+	case "sh", "dash", "ash", "ksh":
+		interp = "bash"
+	case "node":
+		interp = "javascript"
+	}
+	return lexers.Get(interp)
+}
+
+// Renders source lines with colors of chroma theme.
+// Lines are returned as is, if content can't be tokenized.
+func highlightLines(lexer chroma.Lexer, theme string, lines []string) []string {
+	source := strings.Join(lines, "\n")
+	iter, err := chroma.Coalesce(lexer).Tokenise(nil, source)
+	if err != nil {
+		return lines
+	}
+	style := styles.Get(theme) // falls back to default theme for unknown names
+
+	tokenStyles := map[chroma.TokenType]lipgloss.Style{}
+	styleFor := func(tt chroma.TokenType) lipgloss.Style {
+		if s, ok := tokenStyles[tt]; ok {
+			return s
+		}
+		entry := style.Get(tt)
+		s := lipgloss.NewStyle()
+		if entry.Colour.IsSet() {
+			s = s.Foreground(lipgloss.Color(entry.Colour.String()))
+		}
+		s = s.Bold(entry.Bold == chroma.Yes).
+			Italic(entry.Italic == chroma.Yes).
+			Underline(entry.Underline == chroma.Yes)
+		tokenStyles[tt] = s
+		return s
+	}
+
+	res := []string{}
+	var line strings.Builder
+	for _, token := range iter.Tokens() {
+		parts := strings.Split(token.Value, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				res = append(res, line.String())
+				line.Reset()
+			}
+			if part != "" {
+				line.WriteString(styleFor(token.Type).Render(part))
+			}
+		}
+	}
+	res = append(res, line.String())
+	// Lexers may add trailing newline.
+	return res[:min(len(res), len(lines))]
+}
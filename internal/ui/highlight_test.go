@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLexer(t *testing.T) {
+	cases := []struct {
+		name, content, lexer string
+	}{
+		{"main.go", "package main", "Go"},
+		{"conf.yaml", "a: 1", "YAML"},
+		{"schema.sql", "select 1;", "SQL"},
+		{"build", "#!/bin/sh\necho hi", "Bash"},
+		{"script", "#!/usr/bin/env python3\nprint(1)", "Python"},
+	}
+	for _, c := range cases {
+		l := detectLexer(c.name, []byte(c.content))
+		require.NotNil(t, l, c.name)
+		require.Contains(t, l.Config().Name, c.lexer, c.name)
+	}
+	require.Nil(t, detectLexer("notes", []byte("just some words")))
+}
+
+func TestHighlightLines(t *testing.T) {
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	t.Cleanup(func() { lipgloss.SetColorProfile(termenv.Ascii) })
+
+	lines := []string{"package main", "", "/* multi", "line */", "func main() {}"}
+	highlighted := highlightLines(detectLexer("main.go", nil), "monokai", lines)
+
+	require.Len(t, highlighted, len(lines))
+	require.NotEqual(t, lines[0], highlighted[0], "keywords are colored")
+	ansi := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	for i := range lines {
+		require.Equal(t, lines[i], ansi.ReplaceAllString(highlighted[i], ""))
+	}
+	require.True(t, strings.Contains(highlighted[3], "\x1b["), "multi-line tokens are colored on every line")
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+
+	t "github.com/LeperGnome/bt/internal/tree"
+)
+
+// Terminal cell is roughly twice as high as it is wide.
+const cellAspect = 2.0
+
+type ImageProtocol string
+
+const (
+	HalfBlock ImageProtocol = "halfblock"
+	Kitty     ImageProtocol = "kitty"
+	ITerm2    ImageProtocol = "iterm2"
+	Sixel     ImageProtocol = "sixel"
+)
+
+// Renders image file data to fit into given number of terminal cells.
+// Encoding stops early, when ctx is done.
+type imageEncoder func(ctx context.Context, data []byte, heightSymbols, widthSymbols int) (string, error)
+
+type imageRenderer struct {
+	detect func() bool // checks, if terminal supports the protocol
+	encode imageEncoder
+}
+
+var imageRenderers = map[ImageProtocol]imageRenderer{
+	HalfBlock: {detect: func() bool { return true }, encode: halfBlockRepr},
+	Kitty:     {detect: detectKitty, encode: kittyImageRepr},
+	ITerm2:    {detect: detectITerm2, encode: iterm2ImageRepr},
+	Sixel:     {detect: detectSixel, encode: sixelImageRepr},
+}
+
+// Order of auto detection, half-block works everywhere, so it goes last.
+var imageDetectOrder = []ImageProtocol{Kitty, ITerm2, Sixel, HalfBlock}
+
+// Parses image protocol name from config. "auto" (or empty) detects protocol by environment.
+func ParseImageProtocol(name string) (ImageProtocol, error) {
+	if name == "" || name == "auto" {
+		return detectImageProtocol(), nil
+	}
+	if _, ok := imageRenderers[ImageProtocol(name)]; !ok {
+		known := []string{"auto"}
+		for _, p := range imageDetectOrder {
+			known = append(known, string(p))
+		}
+		return HalfBlock, fmt.Errorf("unknown image protocol '%s' (expected one of %s)", name, strings.Join(known, ", "))
+	}
+	return ImageProtocol(name), nil
+}
+
+func detectImageProtocol() ImageProtocol {
+	// tmux doesn't pass graphics through by default.
+	if os.Getenv("TMUX") != "" {
+		return HalfBlock
+	}
+	for _, p := range imageDetectOrder {
+		if imageRenderers[p].detect() {
+			return p
+		}
+	}
+	return HalfBlock
+}
+
+func detectKitty() bool {
+	term, termProgram := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+	return term == "xterm-kitty" || term == "xterm-ghostty" || os.Getenv("KITTY_WINDOW_ID") != "" ||
+		termProgram == "WezTerm" || termProgram == "ghostty"
+}
+func detectITerm2() bool {
+	return os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("LC_TERMINAL") == "iTerm2"
+}
+func detectSixel() bool {
+	term := os.Getenv("TERM")
+	return strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") ||
+		strings.HasPrefix(term, "contour") || os.Getenv("WT_SESSION") != ""
+}
+
+func imagePreviewFunc(protocol ImageProtocol) previewGenFunc {
+	renderer, ok := imageRenderers[protocol]
+	if !ok {
+		renderer = imageRenderers[HalfBlock]
+	}
+	// Images are fit into the preview, so there is nothing to scroll.
+	return func(ctx context.Context, node *t.Node, dim Dimentions, _ Stylesheet, _ int) (string, int) {
+		f, err := node.Open()
+		if err != nil {
+			return err.Error(), 0
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err.Error(), 0
+		}
+		preview, err := renderer.encode(ctx, data, dim.Height, dim.Width)
+		if err != nil {
+			return err.Error(), 0
+		}
+		return preview, 0
+	}
+}
+
+// Returns image size in cells, keeping it's aspect ratio.
+func fitCells(pxWidth, pxHeight, maxCols, maxRows int) (int, int) {
+	if pxWidth <= 0 || pxHeight <= 0 || maxCols <= 0 || maxRows <= 0 {
+		return 0, 0
+	}
+	cols := maxCols
+	rows := int(math.Ceil(float64(cols) * float64(pxHeight) / float64(pxWidth) / cellAspect))
+	if rows > maxRows {
+		rows = maxRows
+		cols = max(int(float64(rows)*float64(pxWidth)/float64(pxHeight)*cellAspect), 1)
+	}
+	return cols, rows
+}
+
+// Reserves rows for an image with label below it. Image is drawn from the label line,
+// after the lines above are painted, so erasing their ends doesn't wipe the image.
+// Cursor is restored afterwards, so the image doesn't break the layout.
+func placeAbove(seq string, rows int, label string) string {
+	return strings.Repeat("\n", rows) +
+		label +
+		ansi.SaveCursor +
+		ansi.CursorUp(rows) +
+		ansi.CursorBackward(ansi.StringWidth(label)) +
+		seq +
+		ansi.RestoreCursor
+}
+
+// Downscales image to given size, averaging pixels. Smaller images are upscaled by repeating pixels.
+func scaleImage(img image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	b := img.Bounds()
+	for y := range height {
+		sy0 := b.Min.Y + y*b.Dy()/height
+		sy1 := max(b.Min.Y+(y+1)*b.Dy()/height, sy0+1)
+		for x := range width {
+			sx0 := b.Min.X + x*b.Dx()/width
+			sx1 := max(b.Min.X+(x+1)*b.Dx()/width, sx0+1)
+
+			var sums [4]uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					r, g, b, a := img.At(sx, sy).RGBA()
+					sums[0] += uint64(r)
+					sums[1] += uint64(g)
+					sums[2] += uint64(b)
+					sums[3] += uint64(a)
+				}
+			}
+			cnt := uint64((sy1 - sy0) * (sx1 - sx0))
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(sums[0] / cnt >> 8),
+				G: uint8(sums[1] / cnt >> 8),
+				B: uint8(sums[2] / cnt >> 8),
+				A: uint8(sums[3] / cnt >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+func imageLabel(width, height int) string {
+	return fmt.Sprintf("%dx%d", width, height)
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"context"
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden image representations")
+
+type encoderTest struct {
+	protocol          ImageProtocol
+	imgPath, reprPath string
+}
+
+var encoderTests = []encoderTest{
+	{Sixel, "coltrane.jpg", "coltrane.sixel"},
+	{Sixel, "lenna.png", "lenna.sixel"},
+	{Sixel, "tiny.png", "tiny.sixel"},
+	{ITerm2, "tiny.png", "tiny.iterm2"},
+	{Kitty, "tiny.png", "tiny.kitty"},
+	{HalfBlock, "tiny.png", "tiny.repr"},
+}
+
+func TestImageEncoders(t *testing.T) {
+	for _, tc := range encoderTests {
+		data, err := os.ReadFile(imgDir + tc.imgPath)
+		require.NoError(t, err)
+
+		repr, err := imageRenderers[tc.protocol].encode(context.Background(), data, height, width)
+		require.NoError(t, err)
+
+		if *update {
+			require.NoError(t, os.WriteFile(reprDir+tc.reprPath, []byte(repr), 0644))
+			continue
+		}
+		want, err := os.ReadFile(reprDir + tc.reprPath)
+		require.NoError(t, err)
+		require.Equal(t, string(want), repr, "repr does not match for %s (%s)", tc.imgPath, tc.protocol)
+	}
+}
+
+func TestImageEncodersCancelled(t *testing.T) {
+	data, err := os.ReadFile(imgDir + "lenna.png")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, p := range []ImageProtocol{Sixel, Kitty, ITerm2} {
+		_, err := imageRenderers[p].encode(ctx, data, height, width)
+		require.ErrorIs(t, err, context.Canceled, p)
+	}
+}
+
+func TestParseImageProtocol(t *testing.T) {
+	p, err := ParseImageProtocol("sixel")
+	require.NoError(t, err)
+	require.Equal(t, Sixel, p)
+
+	t.Setenv("TMUX", "")
+	t.Setenv("TERM", "xterm-kitty")
+	p, err = ParseImageProtocol("auto")
+	require.NoError(t, err)
+	require.Equal(t, Kitty, p)
+
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	p, err = ParseImageProtocol("")
+	require.NoError(t, err)
+	require.Equal(t, HalfBlock, p)
+
+	_, err = ParseImageProtocol("ascii")
+	require.ErrorContains(t, err, "unknown image protocol")
+}
+
+func TestFitCells(t *testing.T) {
+	cols, rows := fitCells(200, 100, 40, 40)
+	require.Equal(t, 40, cols)
+	require.Equal(t, 10, rows)
+
+	cols, rows = fitCells(100, 400, 40, 10)
+	require.Equal(t, 5, cols)
+	require.Equal(t, 10, rows)
+
+	cols, rows = fitCells(100, 100, 0, 10)
+	require.Zero(t, cols)
+	require.Zero(t, rows)
+}
+
+func TestMedianCut(t *testing.T) {
+	red, blue := color.RGBA{250, 0, 0, 255}, color.RGBA{0, 0, 250, 255}
+	pixels := []color.RGBA{red, red, red, blue, {0, 0, 240, 255}}
+
+	palette := medianCut(context.Background(), pixels, 2)
+	require.Len(t, palette, 2)
+	require.Equal(t, red, palette[nearestColor(palette, red)])
+	require.Equal(t, color.RGBA{0, 0, 245, 255}, palette[nearestColor(palette, blue)])
+
+	require.Len(t, medianCut(context.Background(), []color.RGBA{red, red}, 16), 1, "no more colors, than there are")
+}
+
+func TestScaleImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	img.SetRGBA(0, 0, color.RGBA{200, 0, 0, 255})
+	img.SetRGBA(1, 0, color.RGBA{100, 0, 0, 255})
+
+	scaled := scaleImage(img, 2, 1)
+	require.Equal(t, image.Rect(0, 0, 2, 1), scaled.Bounds())
+	require.Equal(t, color.RGBA{75, 0, 0, 127}, scaled.RGBAAt(0, 0))
+}
@@ -0,0 +1,26 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+)
+
+// Displays image with iTerm2 inline images protocol (OSC 1337), terminal decodes the file itself.
+func iterm2ImageRepr(ctx context.Context, data []byte, heightSymbols, widthSymbols int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	cols, rows := fitCells(cfg.Width, cfg.Height, widthSymbols-1, heightSymbols-1)
+	seq := fmt.Sprintf(
+		"\033]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data),
+	)
+	return placeAbove(seq, rows, imageLabel(cfg.Width, cfg.Height)), nil
+}
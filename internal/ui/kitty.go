@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+)
+
+const (
+	kittyChunkSize = 4096
+	// All previews share one image id, so transmitting a new image replaces the previous one.
+	kittyImageID        = 31337
+	kittyTransmitPrefix = "\033_Ga=T"
+	// Deletes bt's image together with it's placements.
+	kittyClearImage = "\033_Ga=d,d=I,i=31337,q=2\033\\"
+)
+
+func kittyImageRepr(ctx context.Context, data []byte, heightSymbols, widthSymbols int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	// Protocol accepts PNG directly, everything else is re-encoded.
+	if format != "png" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		buf := bytes.Buffer{}
+		if err := png.Encode(&buf, img); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	}
+
+	rows := heightSymbols - 1 // For bottom resolution output.
+	cols := widthSymbols - 1  // For border.
+	cols, rows = fitCells(cfg.Width, cfg.Height, cols, rows)
+
+	res := kittyTransmit(data, cols, rows)
+	res += strings.Repeat("\n", rows)
+	res += imageLabel(cfg.Width, cfg.Height)
+	return res, nil
+}
+
+// Splits PNG data into escape sequences, transmitting and displaying it at the cursor.
+// Cursor is not moved, so the image doesn't break the layout.
+func kittyTransmit(data []byte, cols, rows int) string {
+	data64 := base64.StdEncoding.EncodeToString(data)
+	res := strings.Builder{}
+	for chunk := 0; chunk == 0 || chunk*kittyChunkSize < len(data64); chunk++ {
+		end := min((chunk+1)*kittyChunkSize, len(data64))
+		more := 0
+		if end < len(data64) {
+			more = 1
+		}
+		payload := data64[chunk*kittyChunkSize : end]
+		if chunk == 0 {
+			fmt.Fprintf(&res,
+				"\033_Ga=T,f=100,i=%d,p=1,q=2,C=1,c=%d,r=%d,m=%d;%s\033\\",
+				kittyImageID, cols, rows, more, payload,
+			)
+		} else {
+			fmt.Fprintf(&res, "\033_Gm=%d;%s\033\\", more, payload)
+		}
+	}
+	return res.String()
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"context"
+	"encoding/base64"
+	"image/png"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKittyTransmitChunks(t *testing.T) {
+	data := make([]byte, kittyChunkSize) // base64 makes it longer than one chunk
+	repr := kittyTransmit(data, 10, 5)
+
+	chunks := regexp.MustCompile("\033_G([^;]*);([^\033]*)\033\\\\").FindAllStringSubmatch(repr, -1)
+	require.Len(t, chunks, 2)
+	require.Equal(t, "a=T,f=100,i=31337,p=1,q=2,C=1,c=10,r=5,m=1", chunks[0][1])
+	require.Equal(t, "m=0", chunks[1][1])
+	require.Equal(t, base64.StdEncoding.EncodeToString(data), chunks[0][2]+chunks[1][2])
+	require.Zero(t, ansi.StringWidth(repr), "escape sequences don't take space in layout")
+}
+
+func TestKittyReencodesJPEG(t *testing.T) {
+	data, err := os.ReadFile(imgDir + "coltrane.jpg")
+	require.NoError(t, err)
+
+	repr, err := kittyImageRepr(context.Background(), data, height, width)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(repr, kittyTransmitPrefix))
+
+	payload := regexp.MustCompile(";([^\033]*)\033\\\\").FindAllStringSubmatch(repr, -1)
+	encoded := ""
+	for _, p := range payload {
+		encoded += p[1]
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+	_, err = png.DecodeConfig(strings.NewReader(string(decoded)))
+	require.NoError(t, err)
+
+	lines := strings.Split(repr, "\n")
+	require.LessOrEqual(t, len(lines), height)
+	require.Regexp(t, `^\d+x\d+$`, lines[len(lines)-1])
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	t "github.com/LeperGnome/bt/internal/tree"
+)
+
+const (
+	lineIndexStep    = 1000 // lines between remembered positions
+	lineIndexEntries = 64   // files, which positions are remembered
+)
+
+// Sparse index of line positions in a file, so scrolled preview is read starting near the page
+// instead of the beginning of the file. It's valid, while file's size and modification time are same.
+type lineIndex struct {
+	size      int64
+	modTime   time.Time
+	positions []int64 // positions[i] is a position of line i*lineIndexStep
+}
+
+// Indexes of recently previewed files, shared by concurrent preview generations.
+type lineIndexCache struct {
+	mu      sync.Mutex
+	entries map[string]*lineIndex
+	order   []string // keys in order they were added, to drop the oldest one
+}
+
+var lineIndexes = &lineIndexCache{entries: map[string]*lineIndex{}}
+
+// Returns the closest known line before or at line with it's position.
+func (c *lineIndexCache) checkpoint(node *t.Node, line int) (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	idx, ok := c.entries[node.URL()]
+	if !ok || !idx.matches(node) {
+		return 0, 0
+	}
+	i := min(line/lineIndexStep, len(idx.positions)-1)
+	return i * lineIndexStep, idx.positions[i]
+}
+
+// Remembers positions of lines, multiple of lineIndexStep, starting with line first.
+func (c *lineIndexCache) add(node *t.Node, first int, positions []int64) {
+	if len(positions) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := node.URL()
+	idx, ok := c.entries[key]
+	if !ok || !idx.matches(node) {
+		if !ok {
+			c.order = append(c.order, key)
+		}
+		idx = &lineIndex{size: node.Info.Size(), modTime: node.Info.ModTime(), positions: []int64{0}}
+		c.entries[key] = idx
+	}
+	// Positions are continued only without gaps, as they are looked up by index.
+	if skip := len(idx.positions) - first/lineIndexStep; skip >= 0 && skip < len(positions) {
+		idx.positions = append(idx.positions, positions[skip:]...)
+	}
+	for len(c.order) > lineIndexEntries {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (idx *lineIndex) matches(node *t.Node) bool {
+	return idx.size == node.Info.Size() && idx.modTime.Equal(node.Info.ModTime())
+}
@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+const (
+	mdBullet     = "• "
+	mdQuote      = "│ "
+	mdCodeIndent = "  "
+	mdRule       = "─"
+	mdCellSep    = " │ "
+)
+
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser()
+
+// Renders markdown into lines, word-wrapped to width.
+func renderMarkdown(source []byte, style Stylesheet, width int) []string {
+	r := mdRenderer{source: source, style: style, width: max(width, 1)}
+	doc := markdownParser.Parse(text.NewReader(source))
+	for ch := doc.FirstChild(); ch != nil; ch = ch.NextSibling() {
+		if ch != doc.FirstChild() {
+			r.lines = append(r.lines, "")
+		}
+		r.block(ch, "", "")
+	}
+	return r.lines
+}
+
+type mdRenderer struct {
+	source []byte
+	style  Stylesheet
+	width  int
+	lines  []string
+}
+
+// Renders block node. First line is prefixed with first, others with rest.
+func (r *mdRenderer) block(n ast.Node, first, rest string) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		heading := strings.Repeat("#", n.Level) + " " + r.inline(n)
+		r.wrap(r.style.MarkdownHeading.Render(heading), first, rest)
+	case *ast.Paragraph, *ast.TextBlock:
+		r.wrap(r.inline(n), first, rest)
+	case *ast.List:
+		for i, item := 0, n.FirstChild(); item != nil; i, item = i+1, item.NextSibling() {
+			marker := mdBullet
+			if n.IsOrdered() {
+				marker = fmt.Sprintf("%d. ", n.Start+i)
+			}
+			pad := strings.Repeat(" ", ansi.StringWidth(marker))
+			if i > 0 && item.HasBlankPreviousLines() {
+				r.lines = append(r.lines, strings.TrimRight(rest, " "))
+			}
+			if i == 0 {
+				r.blocks(item, first+marker, rest+pad, rest+pad)
+			} else {
+				r.blocks(item, rest+marker, rest+pad, rest+pad)
+			}
+		}
+	case *ast.Blockquote:
+		quote := r.style.MarkdownQuote.Render(mdQuote)
+		r.blocks(n, first+quote, rest+quote, rest+quote)
+	case *ast.FencedCodeBlock:
+		r.code(n, string(n.Language(r.source)), first, rest)
+	case *ast.CodeBlock:
+		r.code(n, "", first, rest)
+	case *ast.ThematicBreak:
+		r.lines = append(r.lines, first+r.style.MarkdownQuote.Render(strings.Repeat(mdRule, r.width-ansi.StringWidth(first))))
+	case *ast.HTMLBlock:
+		r.code(n, "html", first, rest)
+	case *east.Table:
+		r.table(n, first, rest)
+	default:
+		r.blocks(n, first, rest, rest)
+	}
+}
+
+// Renders children blocks, the first one is prefixed with first.
+// Blank lines separate children, except for items of tight lists.
+func (r *mdRenderer) blocks(n ast.Node, first, rest, blank string) {
+	for ch := n.FirstChild(); ch != nil; ch = ch.NextSibling() {
+		if ch == n.FirstChild() {
+			r.block(ch, first, rest)
+			continue
+		}
+		if ch.HasBlankPreviousLines() {
+			r.lines = append(r.lines, strings.TrimRight(blank, " "))
+		}
+		r.block(ch, rest, rest)
+	}
+}
+func (r *mdRenderer) wrap(s, first, rest string) {
+	width := max(r.width-ansi.StringWidth(rest), 1)
+	for i, line := range strings.Split(ansi.Wrap(s, width, ""), "\n") {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		r.lines = append(r.lines, prefix+line)
+	}
+}
+
+// Code isn't wrapped, it's cut by the preview width instead.
+func (r *mdRenderer) code(n ast.Node, lang, first, rest string) {
+	lines := []string{}
+	segments := n.Lines()
+	for i := range segments.Len() {
+		seg := segments.At(i)
+		lines = append(lines, strings.TrimRight(string(seg.Value(r.source)), "\n"))
+	}
+	if lexer := lexers.Get(lang); lang != "" && lexer != nil {
+		lines = highlightLines(lexer, r.style.SyntaxTheme, lines)
+	} else {
+		for i, line := range lines {
+			lines[i] = r.style.MarkdownCode.Render(line)
+		}
+	}
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		r.lines = append(r.lines, prefix+mdCodeIndent+line)
+	}
+}
+
+// Columns are as wide as their widest cells, rows aren't wrapped.
+func (r *mdRenderer) table(n *east.Table, first, rest string) {
+	rows := [][]string{}
+	widths := []int{}
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		cells := []string{}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			c := r.inline(cell)
+			if _, ok := row.(*east.TableHeader); ok {
+				c = lipgloss.NewStyle().Bold(true).Render(c)
+			}
+			if len(widths) <= len(cells) {
+				widths = append(widths, 0)
+			}
+			widths[len(cells)] = max(widths[len(cells)], ansi.StringWidth(c))
+			cells = append(cells, c)
+		}
+		rows = append(rows, cells)
+	}
+	sep := r.style.MarkdownQuote.Render(mdCellSep)
+	for i, cells := range rows {
+		for j, c := range cells {
+			cells[j] = c + strings.Repeat(" ", widths[j]-ansi.StringWidth(c))
+		}
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		r.lines = append(r.lines, prefix+strings.Join(cells, sep))
+		if i == 0 {
+			rule := []string{}
+			for _, w := range widths {
+				rule = append(rule, strings.Repeat(mdRule, w))
+			}
+			r.lines = append(r.lines, rest+r.style.MarkdownQuote.Render(strings.Join(rule, "─┼─")))
+		}
+	}
+}
+
+// Renders inline children of the node into a single styled string.
+func (r *mdRenderer) inline(n ast.Node) string {
+	res := strings.Builder{}
+	for ch := n.FirstChild(); ch != nil; ch = ch.NextSibling() {
+		switch ch := ch.(type) {
+		case *ast.Text:
+			res.Write(ch.Segment.Value(r.source))
+			if ch.HardLineBreak() {
+				res.WriteString("\n")
+			} else if ch.SoftLineBreak() {
+				res.WriteString(" ")
+			}
+		case *ast.String:
+			res.Write(ch.Value)
+		case *ast.CodeSpan:
+			res.WriteString(r.style.MarkdownCode.Render(r.inline(ch)))
+		case *ast.Emphasis:
+			emphasis := lipgloss.NewStyle().Italic(ch.Level == 1).Bold(ch.Level > 1)
+			res.WriteString(emphasis.Render(r.inline(ch)))
+		case *east.Strikethrough:
+			res.WriteString(lipgloss.NewStyle().Strikethrough(true).Render(r.inline(ch)))
+		case *ast.Link:
+			label := r.inline(ch)
+			res.WriteString(r.style.MarkdownLink.Render(label))
+			if dest := string(ch.Destination); dest != label {
+				res.WriteString(" " + r.style.MarkdownQuote.Render("("+dest+")"))
+			}
+		case *ast.AutoLink:
+			res.WriteString(r.style.MarkdownLink.Render(string(ch.URL(r.source))))
+		case *ast.Image:
+			res.WriteString(r.style.MarkdownQuote.Render("[image: " + r.inline(ch) + "]"))
+		case *east.TaskCheckBox:
+			if ch.IsChecked {
+				res.WriteString("[x] ")
+			} else {
+				res.WriteString("[ ] ")
+			}
+		case *ast.RawHTML:
+			segments := ch.Segments
+			for i := range segments.Len() {
+				seg := segments.At(i)
+				res.Write(seg.Value(r.source))
+			}
+		default:
+			res.WriteString(r.inline(ch))
+		}
+	}
+	return res.String()
+}
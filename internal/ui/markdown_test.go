@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	source := "# Title\n\n" +
+		"Some *emphasized* text with `code` and a [link](https://example.com), long enough to be wrapped.\n\n" +
+		"- first\n" +
+		"- second\n" +
+		"  1. nested\n\n" +
+		"> quoted\n\n" +
+		"```go\nfunc main() {}\n```\n\n" +
+		"| key | action |\n| --- | --- |\n| j | down |\n"
+
+	lines := renderMarkdown([]byte(source), DefaultStylesheet, 40)
+	for i, line := range lines {
+		lines[i] = ansi.Strip(line)
+		require.LessOrEqual(t, ansi.StringWidth(lines[i]), 40, lines[i])
+	}
+	require.Equal(t, []string{
+		"# Title",
+		"",
+		"Some emphasized text with code and a",
+		"link (https://example.com), long enough",
+		"to be wrapped.",
+		"",
+		"• first",
+		"• second",
+		"  1. nested",
+		"",
+		"│ quoted",
+		"",
+		"  func main() {}",
+		"",
+		"key │ action",
+		"────┼───────",
+		"j   │ down  ",
+	}, lines)
+}
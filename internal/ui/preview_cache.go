@@ -0,0 +1,66 @@
+package ui
+
+import "container/list"
+
+// LRU cache of generated previews, bounded by number of entries and total size of their content.
+type previewCache struct {
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	order      *list.List // of Preview, most recently used in front
+	entries    map[previewKey]*list.Element
+}
+
+func newPreviewCache(maxEntries, maxBytes int) *previewCache {
+	return &previewCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    map[previewKey]*list.Element{},
+	}
+}
+
+func (c *previewCache) get(key previewKey) (Preview, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return Preview{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(Preview), true
+}
+
+// Returns most recently used page of the same preview, scrolled to any offset.
+func (c *previewCache) anyPage(key previewKey) (Preview, bool) {
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		p := el.Value.(Preview)
+		if k := p.key(); k.path == key.path && k.diff == key.diff && k.raw == key.raw && k.dim == key.dim {
+			return p, true
+		}
+	}
+	return Preview{}, false
+}
+
+// The most recent preview is kept even if it exceeds the limits, otherwise it'd be generated over and over.
+func (c *previewCache) set(p Preview) {
+	if el, ok := c.entries[p.key()]; ok {
+		c.remove(el)
+	}
+	c.entries[p.key()] = c.order.PushFront(p)
+	c.bytes += len(p.Content)
+	for c.order.Len() > 1 && (c.order.Len() > c.maxEntries || c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *previewCache) removePath(path string) {
+	for key, el := range c.entries {
+		if key.path == path {
+			c.remove(el)
+		}
+	}
+}
+func (c *previewCache) remove(el *list.Element) {
+	p := c.order.Remove(el).(Preview)
+	delete(c.entries, p.key())
+	c.bytes -= len(p.Content)
+}
@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/LeperGnome/bt/internal/config"
+	"github.com/LeperGnome/bt/internal/state"
+	tr "github.com/LeperGnome/bt/internal/tree"
+)
+
+func TestPreviewCache(t *testing.T) {
+	dim := Dimentions{Width: 10, Height: 10}
+	c := newPreviewCache(2, 10)
+	a := Preview{Path: "a", Dim: dim, Content: "aaa"}
+	b := Preview{Path: "b", Dim: dim, Content: "bbb"}
+	c.set(a)
+	c.set(b)
+
+	_, ok := c.get(a.key())
+	require.True(t, ok)
+	cc := Preview{Path: "c", Dim: dim, Content: "ccc"}
+	c.set(cc)
+	_, ok = c.get(b.key())
+	require.False(t, ok, "least recently used is evicted by number of entries")
+	_, ok = c.get(a.key())
+	require.True(t, ok)
+
+	c.set(Preview{Path: "d", Dim: dim, Content: "ddddddd"})
+	_, ok = c.get(cc.key())
+	require.False(t, ok, "least recently used is evicted by size")
+	require.Equal(t, 10, c.bytes)
+
+	huge := Preview{Path: "e", Dim: dim, Content: "eeeeeeeeeeeeeeee"}
+	c.set(huge)
+	_, ok = c.get(huge.key())
+	require.True(t, ok, "the most recent preview is kept even if it's too big")
+
+	c.set(Preview{Path: "e", Dim: dim, Offset: 3, Content: "page"})
+	page, ok := c.anyPage(huge.key())
+	require.True(t, ok)
+	require.Equal(t, "page", page.Content)
+
+	c.removePath("e")
+	require.Zero(t, c.order.Len())
+	require.Zero(t, c.bytes)
+}
+
+func TestPreviewGenerationDeduplicated(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello"), 0o644))
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	node := tree.Root.Children[0]
+
+	r := NewRenderer(DefaultStylesheet, 0, true, false, HalfBlock)
+	done := make(chan Preview) // unbuffered, so generation stays in flight until it's received
+	r.PreviewDoneChan, r.previewGenChan = done, done
+	key := previewKey{path: node.Path, dim: Dimentions{Width: 20, Height: 5}}
+	r.generatePreview(node, key)
+	r.generatePreview(node, key)
+	require.Len(t, r.inflight, 1)
+	preview := <-r.PreviewDoneChan
+	require.Contains(t, preview.Content, "hello")
+	require.Eventually(t, func() bool {
+		r.inflightMu.Lock()
+		defer r.inflightMu.Unlock()
+		return len(r.inflight) == 0
+	}, time.Second, time.Millisecond)
+	select {
+	case <-r.PreviewDoneChan:
+		t.Fatal("preview is generated once")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	r.generatePreview(node, key)
+	r.cancelGenerations(func(previewKey) bool { return true })
+	require.Empty(t, r.inflight)
+}
+
+func TestPrefetchNeighbours(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("content of "+name), 0o644))
+	}
+	s, err := state.InitState([]string{dir}, config.BtConfig{})
+	require.NoError(t, err)
+	r := NewRenderer(DefaultStylesheet, 0, true, false, HalfBlock)
+	window := Dimentions{Width: 80, Height: 20}
+
+	screen := r.Render(s, window)
+	require.Contains(t, screen, loadingPlaceholder)
+	selected := s.Tree.GetSelectedChild().Path
+	for range 3 {
+		preview := <-r.PreviewDoneChan
+		before := screen
+		r.SetPreviewCache(preview)
+		screen = r.Render(s, window)
+		if preview.Path == selected {
+			require.Contains(t, screen, "content of a.txt")
+		} else {
+			require.Equal(t, before, screen, "prefetched preview doesn't change the screen")
+		}
+	}
+
+	s.Tree.SelectNextChild()
+	require.Contains(t, r.Render(s, window), "content of b.txt", "preview is ready before selection")
+}
+
+func TestPrefetchSkipsDirSize(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, "file.txt"), []byte("content"), 0o644))
+	}
+	s, err := state.InitState([]string{dir}, config.BtConfig{})
+	require.NoError(t, err)
+	r := NewRenderer(DefaultStylesheet, 0, true, false, HalfBlock)
+	window := Dimentions{Width: 80, Height: 20}
+
+	// Waits for a preview of b, setting all the others.
+	waitPreview := func() Preview {
+		for {
+			preview := <-r.PreviewDoneChan
+			r.SetPreviewCache(preview)
+			if preview.Path == filepath.Join(dir, "b") {
+				return preview
+			}
+		}
+	}
+	r.Render(s, window)
+	prefetched := waitPreview()
+	require.True(t, prefetched.sizePending)
+	require.Contains(t, prefetched.Content, "0 dirs, 1 files\n")
+
+	s.Tree.SelectNextChild()
+	r.Render(s, window)
+	require.False(t, waitPreview().sizePending, "listing is shown first")
+	require.Contains(t, waitPreview().Content, "0 dirs, 1 files, 7")
+}
@@ -1,7 +1,9 @@
 package ui
 
 import (
-	"encoding/base64"
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"image"
 	_ "image/gif"
@@ -9,50 +11,47 @@ import (
 	_ "image/png"
 	"io"
 	"math"
-	"os"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
 	t "github.com/LeperGnome/bt/internal/tree"
 )
 
-type previewGenFunc = func(node *t.Node, dim Dimentions, style Stylesheet) string
+// Generates preview, scrolled down by offset lines (rows of a hex dump for binary files).
+// Returns it with the greatest offset, that still fills the preview, or -1 when it's unknown yet.
+type previewGenFunc = func(ctx context.Context, node *t.Node, dim Dimentions, style Stylesheet, offset int) (string, int)
 
-const (
-	tgpChunkSize = 2048
-	// tgpClearMedia = "\033_Ga=d\033\\" // TODO: TGP support
-)
-
-func GeneratePreview(node *t.Node, dim Dimentions, style Stylesheet) string {
+// With rawMarkdown set, markdown is previewed as source text. Generation stops early, when ctx is done.
+func GeneratePreview(ctx context.Context, node *t.Node, dim Dimentions, offset int, style Stylesheet, images ImageProtocol, rawMarkdown bool) (string, int) {
+	if node.Info.IsDir() {
+		return genDirPreview(ctx, node, dim, style, offset, "")
+	}
 	parts := strings.Split(node.Info.Name(), ".")
 	ext := strings.ToLower(parts[len(parts)-1])
-	f := getPreviewFunc(ext)
-
-	preview := f(node, dim, style)
-	// return kittyClearMedia + preview // TODO: TGP support
-	return preview
+	f := getPreviewFunc(ext, images, rawMarkdown)
+	return f(ctx, node, dim, style, offset)
 }
 
-func getPreviewFunc(getPreviewGenFunc string) previewGenFunc {
-	switch getPreviewGenFunc {
+func getPreviewFunc(ext string, images ImageProtocol, rawMarkdown bool) previewGenFunc {
+	switch ext {
 	case "png", "jpg", "jpeg", "gif":
-		return genHalfBlockPreview
+		return imagePreviewFunc(images)
+	case "md", "markdown":
+		if rawMarkdown {
+			return genTextPreview
+		}
+		return genMarkdownPreview
 	default:
-		return genPlainTextPreview
+		return genTextPreview
 	}
 }
 
-func genHalfBlockPreview(node *t.Node, dim Dimentions, style Stylesheet) string {
-	f, err := os.Open(node.Path)
-	if err != nil {
-		return err.Error()
-	}
-	defer f.Close()
-	preview := imageHalfBlockRepr(f, dim.Height, dim.Width)
-	return preview
+func halfBlockRepr(ctx context.Context, data []byte, heightSymbols, widthSymbols int) (string, error) {
+	return imageHalfBlockRepr(ctx, bytes.NewReader(data), heightSymbols, widthSymbols), nil
 }
 
-func imageHalfBlockRepr(r io.Reader, heightSymbols, widthSymbols int) string {
+func imageHalfBlockRepr(ctx context.Context, r io.Reader, heightSymbols, widthSymbols int) string {
 	img, _, err := image.Decode(r)
 	if err != nil {
 		return err.Error()
@@ -85,6 +84,9 @@ func imageHalfBlockRepr(r io.Reader, heightSymbols, widthSymbols int) string {
 
 	// Loop through sectors.
 	for sy := 0; sy < heightSectors; sy += 2 {
+		if err := ctx.Err(); err != nil {
+			return err.Error()
+		}
 		line := []string{}
 		for sx := range widthSectors {
 			// Loop through pixels within sector.
@@ -132,79 +134,357 @@ func imageHalfBlockRepr(r io.Reader, heightSymbols, widthSymbols int) string {
 	return strings.Join(res, "\n")
 }
 
-func genPlainTextPreview(node *t.Node, dim Dimentions, style Stylesheet) string {
-	buf := make([]byte, previewTextBytesLimit)
-	n, err := node.ReadContent(buf, previewTextBytesLimit)
+// Reads beginning of the file up to limit bytes.
+func readPreviewContent(node *t.Node, limit int64) ([]byte, error) {
+	buf := make([]byte, limit)
+	n, err := node.ReadContent(buf, 0, limit)
 	if err != nil {
-		return ""
+		return nil, err
 	}
 	content := buf[:n]
+	if int64(n) == limit {
+		content = trimIncompleteRune(content)
+	}
+	return content, nil
+}
 
-	contentStyle := style.PlainTextPreview.MaxWidth(dim.Width - 1) // -1 for border...
+// Text file, opened once per preview generation and read sequentially.
+type textReader struct {
+	node *t.Node
+	f    io.ReadCloser
+	r    *bufio.Reader
+	pos  int64
+}
 
-	var contentLines []string
-	if !utf8.Valid(content) {
-		contentLines = []string{binaryContentPlaceholder}
-	} else {
-		contentLines = strings.Split(string(content), "\n")
-		contentLines = contentLines[:max(min(dim.Height, len(contentLines)), 0)]
+func openTextReader(node *t.Node) (*textReader, error) {
+	if !node.Info.Mode().IsRegular() {
+		return nil, fmt.Errorf("file not selected or is irregular")
 	}
+	f, err := node.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &textReader{node: node, f: f, r: bufio.NewReaderSize(f, int(previewTextBytesLimit))}, nil
+}
 
-	return contentStyle.Render(strings.Join(contentLines, "\n"))
+// Returns beginning of the file up to previewTextBytesLimit, without moving the reader.
+func (tr *textReader) head() ([]byte, error) {
+	head, err := tr.r.Peek(int(previewTextBytesLimit))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = bytes.Clone(head)
+	if int64(len(head)) == previewTextBytesLimit {
+		head = trimIncompleteRune(head)
+	}
+	return head, nil
 }
 
-// TODO: TGP support
-func genTPGPreviewPNG(node *t.Node, dim Dimentions, _ Stylesheet) string {
-	preview, err := tgpDirectChunks(node.Path, dim.Height, dim.Width)
-	if err != nil {
-		return err.Error()
+// Moves reader to pos. Archive members can't seek, so they're skipped through,
+// going back reopens them.
+func (tr *textReader) seek(pos int64) error {
+	if pos == tr.pos {
+		return nil
 	}
-	return preview
+	if seeker, ok := tr.f.(io.Seeker); ok {
+		if _, err := seeker.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		tr.r.Reset(tr.f)
+		tr.pos = pos
+		return nil
+	}
+	if pos < tr.pos {
+		f, err := tr.node.Open()
+		if err != nil {
+			return err
+		}
+		tr.f.Close()
+		tr.f, tr.pos = f, 0
+		tr.r.Reset(f)
+	}
+	n, err := tr.r.Discard(int(pos - tr.pos))
+	tr.pos += int64(n)
+	if err == io.EOF {
+		return nil
+	}
+	return err
 }
 
-// TODO: TGP support
-func tgpDirectChunks(path string, height, width int) (string, error) {
-	res := []string{}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+// Reads a line without the line break. Only first previewTextBytesLimit bytes are kept,
+// as lines are cut by the preview width anyway. Reports, whether the line break was found.
+func (tr *textReader) readLine() (string, bool, error) {
+	line := []byte{}
+	for {
+		chunk, err := tr.r.ReadSlice('\n')
+		tr.pos += int64(len(chunk))
+		eol := err == nil
+		if eol {
+			chunk = chunk[:len(chunk)-1]
+		}
+		if room := int(previewTextBytesLimit) - len(line); room > 0 {
+			line = append(line, chunk[:min(len(chunk), room)]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && len(chunk) == 0 && len(line) == 0 {
+			return "", false, io.EOF
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		return string(line), eol, err
 	}
-	data64 := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
-	base64.StdEncoding.Encode(data64, data)
+}
 
-	chunk := 0
+func (tr *textReader) Close() error {
+	return tr.f.Close()
+}
+
+// Reads up to count lines of the file, starting from line offset. Reading starts from the closest
+// position, remembered in line index. Returns lines with the total number of lines, which is -1
+// when the file wasn't read till the end, and the position in bytes after the last returned line.
+func readLines(ctx context.Context, tr *textReader, offset, count int) ([]string, int, int64, error) {
+	line, pos := lineIndexes.checkpoint(tr.node, offset)
+	if err := tr.seek(pos); err != nil {
+		return nil, -1, 0, err
+	}
+	first := line
+	positions := []int64{}
+	defer func() { lineIndexes.add(tr.node, first, positions) }()
 
+	lines := []string{}
 	for {
-		if chunk == 0 && (chunk+1)*tgpChunkSize < len(data64) {
-			chunkData := fmt.Sprintf(
-				"\033_Ga=T,C=1,f=100,r=%d,c=%d,m=1;%s\033\\",
-				height-1, width-1,
-				string(data64[chunk*tgpChunkSize:(chunk+1)*tgpChunkSize]),
-			)
-			res = append(res, chunkData)
-		} else if (chunk+1)*tgpChunkSize < len(data64) {
-			chunkData := fmt.Sprintf(
-				"\033_Gm=1;%s\033\\",
-				string(data64[chunk*tgpChunkSize:(chunk+1)*tgpChunkSize]),
-			)
-			res = append(res, chunkData)
-		} else if chunk == 0 {
-			chunkData := fmt.Sprintf(
-				"\033_Ga=T,C=1,f=100;%s\033\\",
-				string(data64),
-			)
-			res = append(res, chunkData)
-			break
+		if line%lineIndexStep == 0 {
+			positions = append(positions, tr.pos)
+			if err := ctx.Err(); err != nil {
+				return nil, -1, 0, err
+			}
+		}
+		if tr.pos >= previewScanBytesLimit {
+			return lines, -1, tr.pos, nil
+		}
+		text, eol, err := tr.readLine()
+		if err == io.EOF {
+			// Empty file still has a single empty line.
+			if line == 0 {
+				lines = append(lines, "")
+				line++
+			}
+			return lines, line, tr.pos, nil
+		}
+		if err != nil {
+			return nil, -1, 0, err
+		}
+		if line >= offset {
+			lines = append(lines, text)
+		}
+		line++
+		if !eol {
+			return lines, line, tr.pos, nil
+		}
+		if len(lines) == count {
+			return lines, -1, tr.pos, nil
+		}
+	}
+}
+
+// Cuts a page of lines, scrolled down by offset, which is clamped to the last page.
+// Position indicator takes the last line of the page, when lines don't fit.
+func pageLines(lines []string, offset, height int, style Stylesheet) ([]string, int) {
+	if len(lines) <= height {
+		return lines, 0
+	}
+	height = max(height-1, 1)
+	maxOffset := len(lines) - height
+	offset = min(max(offset, 0), maxOffset)
+	page := slices.Clone(lines[offset : offset+height])
+	return append(page, positionIndicator(offset, height, len(lines), style)), maxOffset
+}
+
+// Renders "line N/M (P%)", where N is the first line on the page.
+func positionIndicator(offset, height, total int, style Stylesheet) string {
+	return style.FileType.Render(fmt.Sprintf("line %d/%d (%d%%)", offset+1, total, min(offset+height, total)*100/total))
+}
+
+// Renders text with syntax highlighting, if language is detected, and as plain text otherwise.
+// File is read lazily, so it can be scrolled much further, than beginning of it, used for detection.
+func genTextPreview(ctx context.Context, node *t.Node, dim Dimentions, style Stylesheet, offset int) (string, int) {
+	tr, err := openTextReader(node)
+	if err != nil {
+		return "", 0
+	}
+	defer tr.Close()
+	head, err := tr.head()
+	if err != nil {
+		return "", 0
+	}
+	if !utf8.Valid(head) {
+		return genBinaryPreview(node, head, dim, style, offset)
+	}
+
+	offset = max(offset, 0)
+	lines, total, end, err := readLines(ctx, tr, offset, dim.Height+1) // extra line shows, whether there is more
+	if err != nil {
+		return "", 0
+	}
+	height := max(dim.Height-1, 1) // for position indicator
+	if total >= 0 && offset > 0 && offset > total-height {
+		offset = max(total-height, 0)
+		if lines, total, end, err = readLines(ctx, tr, offset, dim.Height+1); err != nil {
+			return "", 0
+		}
+	}
+	maxOffset := -1
+	if total >= 0 {
+		maxOffset = max(total-height, 0)
+	}
+	if offset == 0 && total >= 0 && total <= dim.Height {
+		height, maxOffset = dim.Height, 0
+	}
+	lines = lines[:min(height, len(lines))]
+	for i, line := range lines {
+		lines[i] = sanitizeLine(line, style.TabWidth, dim.Width-2) // -2 for border and margin
+	}
+
+	contentStyle := style.PlainTextPreview.MaxWidth(dim.Width - 1) // -1 for border...
+	if lexer := detectLexer(node.Info.Name(), head); lexer != nil {
+		lines = highlightLines(lexer, style.SyntaxTheme, lines)
+		contentStyle = style.CodePreview.MaxWidth(dim.Width - 1)
+	}
+	if maxOffset != 0 {
+		indicator := ""
+		if total >= 0 {
+			indicator = positionIndicator(offset, height, total, style)
 		} else {
-			chunkData := fmt.Sprintf(
-				"\033_Gm=0;%s\033\\",
-				string(data64[chunk*tgpChunkSize:]),
-			)
-			res = append(res, chunkData)
-			break
+			// Total is unknown, until the file is read till the end, so percentage is by bytes.
+			percent := int64(100)
+			if size := node.Info.Size(); size > 0 {
+				percent = min(end*100/size, 100)
+			}
+			indicator = style.FileType.Render(fmt.Sprintf("line %d (%d%%)", offset+1, percent))
 		}
-		chunk++
+		lines = append(lines, indicator)
 	}
+	return contentStyle.Render(strings.Join(lines, "\n")), maxOffset
+}
+
+// Renders file type and hex dump, scrolled down by offset rows.
+func genBinaryPreview(node *t.Node, head []byte, dim Dimentions, style Stylesheet, offset int) (string, int) {
+	perRow := int64(hexBytesPerRow(dim.Width - 1)) // -1 for border
+	totalRows := int((node.Info.Size() + perRow - 1) / perRow)
+	rows := max(dim.Height-1, 1) // for type line
+	maxOffset := 0
+	if totalRows > rows {
+		rows = max(rows-1, 1) // for position indicator
+		maxOffset = totalRows - rows
+	}
+	offset = min(max(offset, 0), maxOffset)
+
+	buf := make([]byte, int64(rows)*perRow)
+	n, err := node.ReadContent(buf, int64(offset)*perRow, int64(len(buf)))
+	if err != nil {
+		return "", 0
+	}
+	lines := []string{style.FileType.Render(detectFileType(head))}
+	lines = append(lines, hexDump(buf[:n], int64(offset)*perRow, dim.Width-1, rows)...)
+	if maxOffset > 0 {
+		lines = append(lines, positionIndicator(offset, rows, totalRows, style))
+	}
+	return style.BinaryPreview.MaxWidth(dim.Width - 1).Render(strings.Join(lines, "\n")), maxOffset
+}
 
-	return strings.Join(res, ""), nil
+// Renders markdown, word-wrapped to the preview width.
+func genMarkdownPreview(ctx context.Context, node *t.Node, dim Dimentions, style Stylesheet, offset int) (string, int) {
+	content, err := readPreviewContent(node, previewMarkdownBytesLimit)
+	if err != nil || ctx.Err() != nil {
+		return "", 0
+	}
+	source := strings.Split(string(content), "\n")
+	for i, line := range source {
+		source[i] = sanitizeLine(line, style.TabWidth, 0) // wrapped by renderer
+	}
+	lines := renderMarkdown([]byte(strings.Join(source, "\n")), style, dim.Width-2) // -2 for border and margin
+	lines, maxOffset := pageLines(lines, offset, dim.Height, style)
+	return style.MarkdownPreview.MaxWidth(dim.Width - 1).Render(strings.Join(lines, "\n")), maxOffset
+}
+
+// Renders children of the directory like the tree does, with their counts on top.
+// Size is added to counts, when it's not empty.
+func genDirPreview(ctx context.Context, node *t.Node, dim Dimentions, style Stylesheet, offset int, size string) (string, int) {
+	if err := ctx.Err(); err != nil {
+		return err.Error(), 0
+	}
+	infos, err := node.ReadDir()
+	if err != nil {
+		return err.Error(), 0
+	}
+	slices.SortFunc(infos, t.CompareInfos)
+
+	dirs := 0
+	for _, info := range infos {
+		if info.IsDir() {
+			dirs++
+		}
+	}
+	stats := fmt.Sprintf("%d dirs, %d files", dirs, len(infos)-dirs)
+	if size != "" {
+		stats += ", " + size
+	}
+	children := []string{}
+	for i, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err.Error(), 0
+		}
+		indent := indentCurrent
+		if i == len(infos)-1 {
+			indent = indentCurrentLast
+		}
+		children = append(children, style.TreeIndent.Render(indent)+treeNameStyle(style, info).Render(info.Name()))
+	}
+	children, maxOffset := pageLines(children, offset, dim.Height-1, style) // -1 for stats
+	lines := append([]string{style.FileType.Render(stats)}, children...)
+	return style.DirPreview.MaxWidth(dim.Width - 1).Render(strings.Join(lines, "\n")), maxOffset
+}
+
+// Renders changes of the file in git repository, skipping diff header.
+func genDiffPreview(ctx context.Context, node *t.Node, dim Dimentions, style Stylesheet, offset int) (string, int) {
+	diff, err := t.ReadGitDiff(ctx, node.Path)
+	if err != nil {
+		return err.Error(), 0
+	}
+	lines := []string{}
+	inHunk := false
+	for _, line := range strings.Split(string(diff), "\n") {
+		line = sanitizeLine(line, style.TabWidth, dim.Width-2) // -2 for border and margin
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+			line = style.DiffHunk.Render(line)
+		case !inHunk:
+			continue
+		case strings.HasPrefix(line, "+"):
+			line = style.DiffAdded.Render(line)
+		case strings.HasPrefix(line, "-"):
+			line = style.DiffRemoved.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	lines, maxOffset := pageLines(lines, offset, dim.Height-1, style) // -1 for header
+	lines = append([]string{style.FileType.Render("git diff")}, lines...)
+	return style.DiffPreview.MaxWidth(dim.Width - 1).Render(strings.Join(lines, "\n")), maxOffset
+}
+
+// Drops the tail of a multi-byte rune, cut by the read limit.
+func trimIncompleteRune(content []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(content); i++ {
+		if utf8.RuneStart(content[len(content)-i]) {
+			if !utf8.FullRune(content[len(content)-i:]) {
+				return content[:len(content)-i]
+			}
+			break
+		}
+	}
+	return content
 }
@@ -1,9 +1,17 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+
+	tr "github.com/LeperGnome/bt/internal/tree"
 )
 
 const (
@@ -35,10 +43,114 @@ func TestHalfBlockRepr(t *testing.T) {
 			t.Error(err)
 		}
 
-		repr := imageHalfBlockRepr(i, height, width)
+		repr := imageHalfBlockRepr(context.Background(), i, height, width)
 		if repr != string(o) {
 			fmt.Printf("got:\n%s\nwant:\n%s\n", string(o), repr)
 			t.Fatalf("Repr does not match for %s", tc.imgPath)
 		}
 	}
 }
+
+func TestDirPreview(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), nil, 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub", "inner"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), nil, 0o644))
+
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	preview, maxOffset := GeneratePreview(context.Background(), tree.Root.Children[0], Dimentions{Width: 40, Height: 3}, 0, DefaultStylesheet, HalfBlock, false)
+
+	lines := strings.Split(ansi.Strip(preview), "\n")
+	require.Len(t, lines, 3, "listing is limited by height")
+	require.Contains(t, lines[0], "1 dirs, 2 files")
+	require.Contains(t, lines[1], "├─ inner")
+	require.Contains(t, lines[2], "line 1/3 (33%)")
+	require.Equal(t, 2, maxOffset)
+
+	preview, _ = GeneratePreview(context.Background(), tree.Root.Children[0], Dimentions{Width: 40, Height: 3}, 5, DefaultStylesheet, HalfBlock, false)
+	lines = strings.Split(ansi.Strip(preview), "\n")
+	require.Contains(t, lines[1], "└─ b.txt", "offset is clamped to the last page")
+	require.Contains(t, lines[2], "line 3/3 (100%)")
+}
+
+func TestTextPreviewScroll(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{}
+	for i := range 3000 {
+		lines = append(lines, fmt.Sprintf("line number %d", i))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte(strings.Join(lines, "\n")), 0o644))
+
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	node := tree.Root.Children[0]
+	dim := Dimentions{Width: 40, Height: 5}
+
+	preview, maxOffset := GeneratePreview(context.Background(), node, dim, 0, DefaultStylesheet, HalfBlock, false)
+	got := strings.Split(ansi.Strip(preview), "\n")
+	require.Len(t, got, 5)
+	require.Contains(t, got[0], "line number 0")
+	require.Contains(t, got[4], "line 1 (0%)", "total is unknown before the end is read")
+	require.Equal(t, -1, maxOffset)
+
+	// Far beyond the first chunk of the file.
+	preview, _ = GeneratePreview(context.Background(), node, dim, 2500, DefaultStylesheet, HalfBlock, false)
+	got = strings.Split(ansi.Strip(preview), "\n")
+	require.Contains(t, got[0], "line number 2500")
+	require.Contains(t, got[3], "line number 2503")
+	line, pos := lineIndexes.checkpoint(node, 2500)
+	require.Equal(t, 2000, line, "scrolling continues from the remembered position")
+	require.Equal(t, int64(len(strings.Join(lines[:2000], "\n"))+1), pos)
+
+	preview, maxOffset = GeneratePreview(context.Background(), node, dim, 2998, DefaultStylesheet, HalfBlock, false)
+	got = strings.Split(ansi.Strip(preview), "\n")
+	require.Equal(t, 2996, maxOffset)
+	require.Contains(t, got[0], "line number 2996", "offset is clamped to the last page")
+	require.Contains(t, got[3], "line number 2999")
+	require.Contains(t, got[4], "line 2997/3000 (100%)")
+}
+
+func TestTextPreviewFits(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\n"), 0o644))
+
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	preview, maxOffset := GeneratePreview(context.Background(), tree.Root.Children[0], Dimentions{Width: 40, Height: 5}, 0, DefaultStylesheet, HalfBlock, false)
+	require.Equal(t, 0, maxOffset)
+	require.Equal(t, []string{"one", "two"}, strings.Split(strings.TrimSpace(strings.ReplaceAll(ansi.Strip(preview), "│", "")), "\n"))
+}
+
+func TestBinaryPreviewScroll(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.bin"), data, 0o644))
+
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	dim := Dimentions{Width: 68, Height: 5} // 16 bytes per row
+	preview, maxOffset := GeneratePreview(context.Background(), tree.Root.Children[0], dim, 10, DefaultStylesheet, HalfBlock, false)
+	got := strings.Split(ansi.Strip(preview), "\n")
+	require.Equal(t, 61, maxOffset)
+	require.Contains(t, got[1], "000000a0: a0a1")
+	require.Contains(t, got[4], "line 11/64")
+}
+
+func TestTextPreviewSanitized(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("\x1b[2Jcleared\r\n\tindented\r\n"), 0o644))
+
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	preview, _ := GeneratePreview(context.Background(), tree.Root.Children[0], Dimentions{Width: 40, Height: 5}, 0, DefaultStylesheet, HalfBlock, false)
+	stripped := ansi.Strip(preview)
+	require.Contains(t, stripped, "^[[2Jcleared")
+	require.Contains(t, stripped, "    indented")
+	require.NotContains(t, stripped, "\r")
+	require.NotContains(t, stripped, "\x1b")
+}
@@ -1,12 +1,15 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -18,8 +21,15 @@ import (
 )
 
 const (
-	previewTextBytesLimit int64 = 10_000
-	previewChangBuffer    int   = 20
+	previewTextBytesLimit     int64 = 10_000
+	previewScanBytesLimit     int64 = 64 << 20 // text isn't scrolled further
+	previewMarkdownBytesLimit int64 = 1 << 20
+	previewChangBuffer        int   = 20
+	previewCacheEntries       int   = 256
+	previewCacheBytes         int   = 32 << 20
+	prefetchSiblings          int   = 3 // on each side of the selected child
+	prefetchWorkers           int   = 2
+	dirSizeEntriesLimit       int   = 100_000
 
 	minHeight = 10
 	minWidth  = 10
@@ -30,11 +40,12 @@ const (
 	indentCurrentLast   = "└─ "
 	indentEmpty         = "   "
 	emptydirContentName = "..."
+	gitDirtyMarker      = "•"
 
-	tooSmall                 = "too small =("
-	binaryContentPlaceholder = "<binary content>"
-	loadingPlaceholder       = "Loading.."
-	helpPreview              = "Press ? to toggle help"
+	tooSmall             = "too small =("
+	loadingPlaceholder   = "Loading.."
+	searchingPlaceholder = "searching.."
+	helpPreview          = "Press ? to toggle help"
 )
 
 type Dimentions struct {
@@ -44,8 +55,27 @@ type Dimentions struct {
 
 type Preview struct {
 	Path    string
+	Diff    bool // git diff of the file instead of it's content
+	Raw     bool // markdown source instead of rendered one
+	Offset  int  // lines, preview is scrolled down by
 	Dim     Dimentions
 	Content string
+
+	MaxOffset int // greatest offset, which still fills the preview, -1 when unknown
+
+	sizePending bool // directory size is skipped, when preview is prefetched
+}
+
+type previewKey struct {
+	path   string
+	diff   bool
+	raw    bool
+	offset int
+	dim    Dimentions
+}
+
+func (p Preview) key() previewKey {
+	return previewKey{p.Path, p.Diff, p.Raw, p.Offset, p.Dim}
 }
 
 type Renderer struct {
@@ -53,9 +83,13 @@ type Renderer struct {
 	EdgePadding int
 
 	PreviewDoneChan <-chan Preview
-	previewCache    map[string]Preview // TODO: limit cache size somehow? Queue~ map?
+	previewCache    *previewCache
 	previewGenChan  chan<- Preview
+	inflight        map[previewKey]context.CancelFunc // previews being generated
+	inflightMu      sync.Mutex
+	prefetchJobs    chan prefetchJob
 	previewEnabled  bool
+	imageProtocol   ImageProtocol
 
 	highlightCurrentIndent bool
 
@@ -67,25 +101,35 @@ func NewRenderer(
 	edgePadding int,
 	previewEnabled bool,
 	highlightCurrentIndent bool,
+	imageProtocol ImageProtocol,
 ) *Renderer {
 	previewChan := make(chan Preview, previewChangBuffer)
-	return &Renderer{
+	r := &Renderer{
 		Style:                  style,
 		EdgePadding:            edgePadding,
 		PreviewDoneChan:        previewChan,
-		previewCache:           map[string]Preview{},
+		previewCache:           newPreviewCache(previewCacheEntries, previewCacheBytes),
+		inflight:               map[previewKey]context.CancelFunc{},
+		prefetchJobs:           make(chan prefetchJob, 2*prefetchSiblings),
 		previewGenChan:         previewChan,
 		previewEnabled:         previewEnabled,
+		imageProtocol:          imageProtocol,
 		highlightCurrentIndent: highlightCurrentIndent,
 	}
+	for range prefetchWorkers {
+		go r.prefetchWorker()
+	}
+	return r
 }
 
 func (r *Renderer) SetPreviewCache(preivew Preview) {
-	r.previewCache[preivew.Path] = preivew
+	r.previewCache.set(preivew)
 }
 
+// Previews in progress are cancelled too, as they may be outdated already.
 func (r *Renderer) RemovePreviewCache(path string) {
-	delete(r.previewCache, path)
+	r.previewCache.removePath(path)
+	r.cancelGenerations(func(key previewKey) bool { return key.path == path })
 }
 
 func (r *Renderer) Render(s *state.State, window Dimentions) string {
@@ -99,20 +143,25 @@ func (r *Renderer) Render(s *state.State, window Dimentions) string {
 	// left for tree, right for file preview
 	sectionWidth := int(math.Floor(0.5 * float64(window.Width)))
 
-	renderedTree := r.renderTree(s.Tree, Dimentions{Height: window.Height - headLen, Width: sectionWidth})
+	var renderedTree string
+	if s.OpBuf == state.Find && s.Find != nil {
+		renderedTree = r.renderFindResults(s.Find, Dimentions{Height: window.Height - headLen, Width: sectionWidth})
+	} else {
+		renderedTree = r.renderTree(s.Tree, Dimentions{Height: window.Height - headLen, Width: sectionWidth}, s.OpBuf == state.Search)
+	}
 
 	var rightPane string
 
 	if s.HelpToggle {
-		renderedHelp, helpLen := r.renderHelp(sectionWidth)
+		renderedHelp, helpLen := r.renderHelp(s.Keymap, sectionWidth)
 		if r.previewEnabled {
-			renderedContent := r.renderSelectedFileContent(s.Tree, Dimentions{Height: window.Height - headLen - helpLen, Width: sectionWidth})
+			renderedContent := r.renderSelectedFileContent(s, Dimentions{Height: window.Height - headLen - helpLen, Width: sectionWidth})
 			rightPane = lipgloss.JoinVertical(lipgloss.Left, renderedHelp, renderedContent)
 		} else {
 			rightPane = renderedHelp
 		}
 	} else if r.previewEnabled {
-		renderedContent := r.renderSelectedFileContent(s.Tree, Dimentions{Height: window.Height - headLen, Width: sectionWidth})
+		renderedContent := r.renderSelectedFileContent(s, Dimentions{Height: window.Height - headLen, Width: sectionWidth})
 		rightPane = renderedContent
 	}
 
@@ -155,10 +204,33 @@ func (r *Renderer) renderHeading(s *state.State, width int) (string, int) {
 			operationBar += fmt.Sprintf(" [%s]", markedPath)
 		}
 	}
+	if pending := s.PendingKeys(); pending != "" {
+		continuations := []string{}
+		for _, c := range s.Keymap.Continuations(pending) {
+			continuations = append(continuations, fmt.Sprintf("%s %s", c.Keys, c.Desc))
+		}
+		operationBar += fmt.Sprintf(" %s → %s", pending, strings.Join(continuations, ", "))
+	}
 	if s.OpBuf.IsInput() {
 		operationBar += fmt.Sprintf(" │ %s │", r.Style.OperationBarInput.Render(string(s.InputBuf)))
 	}
+	if search := s.Tree.Search; search != nil && search.Query != "" {
+		if s.OpBuf != state.Search {
+			operationBar += fmt.Sprintf("/%s", search.Query)
+		}
+		if len(search.Matches) == 0 {
+			operationBar += " [no matches]"
+		} else {
+			operationBar += fmt.Sprintf(" [%d/%d]", search.Current+1, len(search.Matches))
+		}
+	}
 
+	if loc := s.Tree.Location(); loc != "" {
+		path = loc + " " + path
+	}
+	if len(s.Trees) > 1 {
+		path = fmt.Sprintf("[%d/%d] %s", s.RootIdx()+1, len(s.Trees), path)
+	}
 	rawPath := "> " + path
 
 	finfo := fmt.Sprintf(
@@ -184,27 +256,10 @@ func (r *Renderer) renderHeading(s *state.State, width int) (string, int) {
 	return strings.Join(header, "\n"), len(header)
 }
 
-func (r *Renderer) renderHelp(width int) (string, int) {
-	help := []string{
-		"j / arr down     Select next child",
-		"k / arr up       Select previous child",
-		"h / arr left     Move up a dir",
-		"l / arr right    Enter selected directory",
-		"tab              Mark selected child and move down",
-		"shift+tab        Mark selected child and move up",
-		"d                Move marked children (then 'p' to paste)",
-		"y                Copy marked children (then 'p' to paste)",
-		"D                Delete marked child",
-		"if / id          Create file (if) / directory (id) in current directory",
-		"r                Rename selected child",
-		"e                Edit selected file in $EDITOR",
-		"gg               Go to top most child in current directory",
-		"G                Go to last child in current directory",
-		"H                Toggle hidden files in current directory",
-		"enter            Open / close selected directory or open file (xdg-open / open)",
-		"esc              Clear error message / stop current operation / drop marks",
-		"?                Toggle help",
-		"q / ctrl+c       Exit",
+func (r *Renderer) renderHelp(keymap state.Keymap, width int) (string, int) {
+	help := []string{}
+	for _, line := range keymap.Help() {
+		help = append(help, fmt.Sprintf("%-16s %s", line.Keys, line.Desc))
 	}
 	return r.Style.
 		HelpContent.
@@ -213,8 +268,8 @@ func (r *Renderer) renderHelp(width int) (string, int) {
 		Render(strings.Join(help, "\n")), len(help) + 1 // +1 for border
 }
 
-func (r *Renderer) renderTree(tree *t.Tree, dim Dimentions) string {
-	renderedTreeLines, selectedRow := r.renderTreeFull(tree, dim.Width)
+func (r *Renderer) renderTree(tree *t.Tree, dim Dimentions, filter bool) string {
+	renderedTreeLines, selectedRow := r.renderTreeFull(tree, dim.Width, filter)
 	croppedTreeLines := r.cropTree(renderedTreeLines, selectedRow, dim.Height)
 
 	treeStyle := lipgloss.
@@ -225,26 +280,219 @@ func (r *Renderer) renderTree(tree *t.Tree, dim Dimentions) string {
 	return treeStyle.Render(strings.Join(croppedTreeLines, "\n"))
 }
 
-func (r *Renderer) renderSelectedFileContent(tree *t.Tree, dim Dimentions) string {
-	ch := tree.GetSelectedChild()
+// Renders flat list of find results, keeping selected one in the middle.
+func (r *Renderer) renderFindResults(find *state.FindState, dim Dimentions) string {
+	status := fmt.Sprintf("%d/%d", len(find.Matches), find.Total)
+	if !find.Done {
+		status += " " + searchingPlaceholder
+	}
+	lines := []string{r.Style.FindStatus.Render(status)}
+
+	height := dim.Height - 1
+	offset := max(min(find.Selected-height/2, len(find.Matches)-height), 0)
+	for i := offset; i < min(offset+height, len(find.Matches)); i++ {
+		m := find.Matches[i]
+		name := m.RelPath
+		if utf8.RuneCountInString(name) > dim.Width-6 { // 6 = len([]rune{"... <-"})
+			name = string([]rune(name)[:max(0, dim.Width-6)]) + "..."
+		}
+		style := r.Style.TreeRegularFileName
+		if m.Result.IsDir {
+			style = r.Style.TreeDirecotryName
+		}
+		repr := r.renderSearchMatch(name, style, m.Positions)
+		if i == find.Selected {
+			repr += r.Style.TreeSelectionArrow.Render(arrow)
+		}
+		lines = append(lines, repr)
+	}
+
+	return lipgloss.
+		NewStyle().
+		MaxWidth(dim.Width).
+		MarginRight(dim.Width).
+		Render(strings.Join(lines, "\n"))
+}
+
+func (r *Renderer) renderSelectedFileContent(s *state.State, dim Dimentions) string {
+	content := r.selectedFileContent(s, dim)
+	// Terminal keeps showing an image until it's deleted.
+	if r.imageProtocol == Kitty && !strings.HasPrefix(content, kittyTransmitPrefix) {
+		content = kittyClearImage + content
+	}
+	return content
+}
+
+func (r *Renderer) selectedFileContent(s *state.State, dim Dimentions) string {
+	ch := s.Tree.GetSelectedChild()
 	if ch == nil {
 		return ""
 	}
-	preview, ok := r.previewCache[ch.Path]
-	if !ok || preview.Dim != dim {
-		// Async preview generation. Main thread will read from preview channel and call Update,
-		// which will then set the cache and call Render.
+	key := previewKeyOf(s, ch, s.PreviewOffset, dim)
+	wanted := map[previewKey]bool{key: true}
+	neighbours := s.Tree.GetSelectedChildNeighbours(prefetchSiblings)
+	neighbourKeys := []previewKey{}
+	for _, n := range neighbours {
+		k := previewKeyOf(s, n, 0, dim)
+		wanted[k] = true
+		neighbourKeys = append(neighbourKeys, k)
+	}
+	// Selection moved on, so previews of children far from it aren't needed anymore.
+	r.cancelGenerations(func(k previewKey) bool { return !wanted[k] })
+
+	preview, ok := r.previewCache.get(key)
+	if !ok || preview.sizePending {
+		r.generatePreview(ch, key)
+	}
+	for i, n := range neighbours {
+		r.prefetchPreview(n, neighbourKeys[i])
+	}
+	if ok {
+		return preview.Content
+	}
+	// While scrolling, the page shown before stays, until the next one is ready.
+	if preview, ok := r.previewCache.anyPage(key); ok {
+		return preview.Content
+	}
+	return loadingPlaceholder
+}
+
+// Key of the preview of node, as it's shown, when node is selected.
+func previewKeyOf(s *state.State, node *t.Node, offset int, dim Dimentions) previewKey {
+	diff := s.DiffPreview && node.IsLocal() && !node.Info.IsDir() && hasGitDiff(s.Tree.Git.State(node.Path))
+	return previewKey{node.Path, diff, s.RawMarkdown, offset, dim}
+}
+
+// Async preview generation. Main thread will read from preview channel and call Update,
+// which will then set the cache and call Render.
+func (r *Renderer) generatePreview(ch *t.Node, key previewKey) {
+	ctx, ok := r.startGeneration(key)
+	if !ok {
+		return
+	}
+	// Node is refreshed by the main thread, while preview is generated.
+	node := *ch
+	go r.runGeneration(ctx, &node, key, false)
+}
+
+type prefetchJob struct {
+	ctx  context.Context
+	node t.Node
+	key  previewKey
+}
+
+// Generates preview of a child, which isn't selected yet, on one of prefetch workers.
+func (r *Renderer) prefetchPreview(ch *t.Node, key previewKey) {
+	if _, ok := r.previewCache.get(key); ok {
+		return
+	}
+	ctx, ok := r.startGeneration(key)
+	if !ok {
+		return
+	}
+	select {
+	case r.prefetchJobs <- prefetchJob{ctx, *ch, key}:
+	default:
+		// Workers are busy, preview is generated, when the child gets selected.
+		r.cancelGenerations(func(k previewKey) bool { return k == key })
+	}
+}
+func (r *Renderer) prefetchWorker() {
+	for job := range r.prefetchJobs {
+		r.runGeneration(job.ctx, &job.node, job.key, true)
+	}
+}
 
-		// TODO: On long preview generaitons I can potentially spawn a lot of gorutines
-		// generating preview for the same file, if i'll go back and forth selecting it and the neighbor.
-		// I can solve it by using some locking mechanism.
-		go func() {
-			preview := GeneratePreview(ch, dim, r.Style)
-			r.previewGenChan <- Preview{Content: preview, Dim: dim, Path: ch.Path}
-		}()
-		return loadingPlaceholder
+// Only one generation runs for a key, until it's done or cancelled.
+func (r *Renderer) startGeneration(key previewKey) (context.Context, bool) {
+	r.inflightMu.Lock()
+	defer r.inflightMu.Unlock()
+	if _, ok := r.inflight[key]; ok {
+		return nil, false
 	}
-	return preview.Content
+	ctx, cancel := context.WithCancel(context.Background())
+	r.inflight[key] = cancel
+	return ctx, true
+}
+
+// Prefetched directories aren't walked for their size, it's calculated, when they get selected.
+func (r *Renderer) runGeneration(ctx context.Context, node *t.Node, key previewKey, prefetch bool) {
+	defer r.generationDone(ctx, key)
+	if ctx.Err() != nil {
+		return
+	}
+	var preview string
+	var maxOffset int
+	if key.diff {
+		preview, maxOffset = genDiffPreview(ctx, node, key.dim, r.Style, key.offset)
+	} else {
+		preview, maxOffset = GeneratePreview(ctx, node, key.dim, key.offset, r.Style, r.imageProtocol, key.raw)
+	}
+	sized := node.Info.IsDir() && !node.IsRemote()
+	p := newPreview(key, maxOffset, preview)
+	p.sizePending = sized && prefetch
+	if !r.sendPreview(ctx, p) {
+		return
+	}
+
+	// Listing is shown right away, size of the directory follows.
+	if sized && !prefetch {
+		size, complete := node.DiskUsage(ctx, dirSizeEntriesLimit)
+		sizeRepr := formatSize(float64(size), 1024.0)
+		if !complete {
+			sizeRepr = "> " + sizeRepr
+		}
+		preview, maxOffset := genDirPreview(ctx, node, key.dim, r.Style, key.offset, sizeRepr)
+		r.sendPreview(ctx, newPreview(key, maxOffset, preview))
+	}
+}
+
+// Results of cancelled generations are dropped, as they may be incomplete.
+func (r *Renderer) sendPreview(ctx context.Context, preview Preview) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case r.previewGenChan <- preview:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (r *Renderer) generationDone(ctx context.Context, key previewKey) {
+	r.inflightMu.Lock()
+	defer r.inflightMu.Unlock()
+	// Key could be taken by another generation, if this one was cancelled.
+	if ctx.Err() == nil {
+		r.inflight[key]()
+		delete(r.inflight, key)
+	}
+}
+
+func (r *Renderer) cancelGenerations(match func(key previewKey) bool) {
+	r.inflightMu.Lock()
+	defer r.inflightMu.Unlock()
+	for key, cancel := range r.inflight {
+		if match(key) {
+			cancel()
+			delete(r.inflight, key)
+		}
+	}
+}
+
+// Offset past the end of the file is clamped by generators, so preview is cached under the clamped one.
+func newPreview(key previewKey, maxOffset int, content string) Preview {
+	offset := key.offset
+	if maxOffset >= 0 {
+		offset = min(offset, maxOffset)
+	}
+	return Preview{Path: key.path, Diff: key.diff, Raw: key.raw, Offset: offset, MaxOffset: maxOffset, Dim: key.dim, Content: content}
+}
+
+// Tracked files with changes against HEAD.
+func hasGitDiff(state t.GitState) bool {
+	return state == t.GitModified || state == t.GitStaged || state == t.GitConflicted
 }
 
 // Crops tree lines, such that current line is visible and view is consistent.
@@ -269,7 +517,8 @@ func (r *Renderer) cropTree(lines []string, currentLine int, height int) []strin
 }
 
 // Returns lines as slice and index of selected line.
-func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
+// With filter set, only search matches and their parents are rendered.
+func (r *Renderer) renderTreeFull(tree *t.Tree, width int, filter bool) ([]string, int) {
 	linen := -1
 	currentLine := 0
 
@@ -282,15 +531,24 @@ func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
 	s := stack.NewStack(stackEl{tree.Root, []string{""}, false})
 
 	selected := tree.GetSelectedChild()
+	search := tree.Search
+	filter = filter && search != nil && search.Query != ""
+	hidden := func(node *t.Node) bool {
+		return filter && node != tree.Root && node != selected && !search.Visible(node.Path)
+	}
 
 	for s.Len() > 0 {
 		el := s.Pop()
-		linen += 1
 
 		node := el.node
 		isLast := el.isLast
 		parentIndent := el.parentIndent
 
+		if node != nil && hidden(node) {
+			continue
+		}
+		linen += 1
+
 		if node == nil {
 			continue
 		}
@@ -316,6 +574,11 @@ func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
 
 		indentRuneCount := (len(parentIndent) - 1) * utf8.RuneCountInString(indentCurrent) // Hacky
 
+		gitMarker := r.renderGitMarker(tree.Git, node)
+		if gitMarker != "" {
+			indentRuneCount += 2 // space and marker
+		}
+
 		// Making name
 		name := node.Info.Name()
 		nameRuneCountNoStyle := utf8.RuneCountInString(name)
@@ -324,12 +587,15 @@ func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
 			name = string([]rune(name)[:max(0, width-indentRuneCount-6)]) + "..."
 		}
 
-		if node.Info.IsDir() {
-			name = r.Style.TreeDirecotryName.Render(name)
-		} else if node.Info.Mode()&os.ModeSymlink == os.ModeSymlink {
-			name = r.Style.TreeLinkName.Render(name)
+		nameStyle := treeNameStyle(r.Style, node.Info)
+		if search != nil {
+			if m, ok := search.MatchFor(node.Path); ok {
+				name = r.renderSearchMatch(name, nameStyle, m.Positions)
+			} else {
+				name = nameStyle.Render(name)
+			}
 		} else {
-			name = r.Style.TreeRegularFileName.Render(name)
+			name = nameStyle.Render(name)
 		}
 
 		if slices.Contains(tree.Marked, node) {
@@ -341,6 +607,9 @@ func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
 		if !node.ShowsHidden() && node.Info.IsDir() {
 			repr += "◦"
 		}
+		if gitMarker != "" {
+			repr += " " + gitMarker
+		}
 
 		if tree.GetSelectedChild() == node {
 			repr += r.Style.TreeSelectionArrow.Render(arrow)
@@ -355,15 +624,81 @@ func (r *Renderer) renderTreeFull(tree *t.Tree, width int) ([]string, int) {
 				lines = append(lines, emptyIndent+emptydirContentName+r.Style.TreeSelectionArrow.Render(arrow))
 				currentLine = linen + 1
 			}
+			// Last child is the last one, that is rendered.
+			last := len(node.Children) - 1
+			for last >= 0 && hidden(node.Children[last]) {
+				last--
+			}
 			for i := len(node.Children) - 1; i >= 0; i-- {
 				ch := node.Children[i]
-				s.Push(stackEl{ch, parentIndent, i == len(node.Children)-1})
+				s.Push(stackEl{ch, parentIndent, i == last})
 			}
 		}
 	}
 	return lines, currentLine
 }
 
+func treeNameStyle(style Stylesheet, info fs.FileInfo) lipgloss.Style {
+	if info.IsDir() {
+		return style.TreeDirecotryName
+	} else if info.Mode()&os.ModeSymlink == os.ModeSymlink {
+		return style.TreeLinkName
+	}
+	return style.TreeRegularFileName
+}
+
+// Renders git state of the node, directories with changes inside get a dirty marker.
+func (r *Renderer) renderGitMarker(git *t.GitStatus, node *t.Node) string {
+	var style lipgloss.Style
+	var marker string
+	switch git.State(node.Path) {
+	case t.GitClean:
+		return ""
+	case t.GitIgnored:
+		style, marker = r.Style.GitIgnored, "!"
+	case t.GitUntracked:
+		style, marker = r.Style.GitUntracked, "?"
+	case t.GitStaged:
+		style, marker = r.Style.GitStaged, "+"
+	case t.GitModified:
+		style, marker = r.Style.GitModified, "M"
+	case t.GitConflicted:
+		style, marker = r.Style.GitConflicted, "U"
+	}
+	if git.IsDirty(node.Path) {
+		marker = gitDirtyMarker
+	}
+	return style.Render(marker)
+}
+
+// Renders name, highlighting matched runes.
+func (r *Renderer) renderSearchMatch(name string, style lipgloss.Style, positions []int) string {
+	matchStyle := r.Style.TreeSearchMatch.Inherit(style)
+	res := strings.Builder{}
+	segment := []rune{}
+	segmentMatched := false
+	flush := func() {
+		if len(segment) == 0 {
+			return
+		}
+		if segmentMatched {
+			res.WriteString(matchStyle.Render(string(segment)))
+		} else {
+			res.WriteString(style.Render(string(segment)))
+		}
+		segment = segment[:0]
+	}
+	for i, ch := range []rune(name) {
+		if matched := slices.Contains(positions, i); matched != segmentMatched {
+			flush()
+			segmentMatched = matched
+		}
+		segment = append(segment, ch)
+	}
+	flush()
+	return res.String()
+}
+
 var sizes = [...]string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
 
 func formatSize(s float64, base float64) string {
@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	tr "github.com/LeperGnome/bt/internal/tree"
+)
+
+func TestRenderTreeFilteredLastChild(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"match.txt", "other.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	tree, _, err := tr.InitTree(tr.NewOSFS(), dir, nil)
+	require.NoError(t, err)
+	tree.StartSearch(false)
+	tree.UpdateSearch("match")
+
+	r := NewRenderer(DefaultStylesheet, 0, false, false, HalfBlock)
+	lines, _ := r.renderTreeFull(tree, 80, true)
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[1], indentCurrentLast, "filtered out sibling doesn't count")
+	require.Contains(t, lines[1], "match.txt")
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Makes a line of file content safe to write into the terminal. Tabs are expanded to the next
+// tab stop of every tabWidth columns, other control characters are shown in caret notation
+// (^[ for escape, ^M for carriage return) and 8-bit ones in hex (<9b>). Carriage return of
+// CRLF line ending is dropped.
+// Positive width cuts the line at that many columns. Wide runes, which don't fit entirely, are dropped.
+func sanitizeLine(line string, tabWidth, width int) string {
+	tabWidth = max(tabWidth, 1)
+	line = strings.TrimSuffix(line, "\r")
+	res := strings.Builder{}
+	col := 0
+	for _, r := range line {
+		var repr string
+		switch {
+		case r == '\t':
+			repr = strings.Repeat(" ", tabWidth-col%tabWidth)
+		case r < 0x20:
+			repr = "^" + string(r+0x40)
+		case r == 0x7f:
+			repr = "^?"
+		case r >= 0x80 && r < 0xa0:
+			repr = fmt.Sprintf("<%02x>", r)
+		default:
+			repr = string(r) // invalid bytes come as utf8.RuneError, so they're replaced too
+		}
+		w := len(repr) // everything but printable non-ASCII runes is ASCII
+		if r >= 0xa0 {
+			w = runewidth.RuneWidth(r)
+		}
+		if width > 0 && col+w > width {
+			break
+		}
+		res.WriteString(repr)
+		col += w
+	}
+	return res.String()
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeLine(t *testing.T) {
+	cases := []struct {
+		line     string
+		tabWidth int
+		width    int
+		want     string
+	}{
+		{"plain", 4, 0, "plain"},
+		{"\x1b[31mred\x1b[0m", 4, 0, "^[[31mred^[[0m"},
+		{"crlf\r", 4, 0, "crlf"},
+		{"a\rb", 4, 0, "a^Mb"},
+		{"a\x00b\x7f", 4, 0, "a^@b^?"},
+		{"\u009b31m", 4, 0, "<9b>31m"},
+		{"\tx", 4, 0, "    x"},
+		{"ab\tx", 4, 0, "ab  x"},
+		{"abcd\tx", 4, 0, "abcd    x"},
+		{"a\tb", 8, 0, "a       b"},
+		{"a\tb", 0, 0, "a b"},
+		{"日本語\tx", 4, 0, "日本語  x"},
+		{"bad\xffbyte", 4, 0, "bad�byte"},
+		{"abcdef", 4, 4, "abcd"},
+		{"日本語", 4, 5, "日本"},
+		{"ab\x1b", 4, 3, "ab"},
+		{"\t\tx", 4, 6, "    "},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, sanitizeLine(c.line, c.tabWidth, c.width), "%q", c.line)
+	}
+}
+
+func FuzzSanitizeLine(f *testing.F) {
+	f.Add("\x1b[31mred\x1b[0m", 4, 10)
+	f.Add("a\tb\tc\r", 8, 0)
+	f.Add("日本語のテキスト", 4, 7)
+	f.Add("\xff\xfe\u009b\u0085", 2, 3)
+	f.Fuzz(func(t *testing.T, line string, tabWidth, width int) {
+		tabWidth, width = tabWidth%64, width%1024
+		res := sanitizeLine(line, tabWidth, width)
+		require.True(t, utf8.ValidString(res))
+		cols := 0
+		for _, r := range res {
+			if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+				t.Fatalf("control character %U in %q", r, res)
+			}
+			cols += runewidth.RuneWidth(r)
+		}
+		if width > 0 {
+			require.LessOrEqual(t, cols, width)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"slices"
+	"strings"
+)
+
+const (
+	// Cell size in pixels is unknown without querying the terminal, assuming common one.
+	sixelCellWidth  = 10
+	sixelCellHeight = 20
+	sixelMaxColors  = 256
+)
+
+// Displays image as DEC sixel graphics with palette, quantized by median cut.
+func sixelImageRepr(ctx context.Context, data []byte, heightSymbols, widthSymbols int) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	b := img.Bounds()
+	cols, rows := fitCells(b.Dx(), b.Dy(), widthSymbols-1, heightSymbols-1)
+	if cols == 0 || rows == 0 {
+		return imageLabel(b.Dx(), b.Dy()), nil
+	}
+	scaled := scaleImage(img, cols*sixelCellWidth, rows*sixelCellHeight)
+	seq, err := encodeSixel(ctx, scaled, sixelMaxColors)
+	if err != nil {
+		return "", err
+	}
+	return placeAbove(seq, rows, imageLabel(b.Dx(), b.Dy())), nil
+}
+
+func encodeSixel(ctx context.Context, img *image.RGBA, maxColors int) (string, error) {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+
+	opaque := []color.RGBA{}
+	for y := range height {
+		for x := range width {
+			if c := img.RGBAAt(b.Min.X+x, b.Min.Y+y); c.A >= 0x80 {
+				opaque = append(opaque, c)
+			}
+		}
+	}
+	palette := medianCut(ctx, opaque, maxColors)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// Palette index of every pixel, -1 for transparent ones.
+	indices := make([]int, width*height)
+	nearest := map[color.RGBA]int{}
+	for y := range height {
+		for x := range width {
+			c := img.RGBAAt(b.Min.X+x, b.Min.Y+y)
+			if c.A < 0x80 {
+				indices[y*width+x] = -1
+				continue
+			}
+			idx, ok := nearest[c]
+			if !ok {
+				idx = nearestColor(palette, c)
+				nearest[c] = idx
+			}
+			indices[y*width+x] = idx
+		}
+	}
+
+	res := strings.Builder{}
+	// P2=1 keeps pixels, which are not painted, transparent.
+	fmt.Fprintf(&res, "\033P0;1;0q\"1;1;%d;%d", width, height)
+	for i, c := range palette {
+		fmt.Fprintf(&res, "#%d;2;%d;%d;%d", i, percent(c.R), percent(c.G), percent(c.B))
+	}
+	band := make([]byte, width)
+	for y0 := 0; y0 < height; y0 += 6 {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		used := []int{}
+		for dy := 0; dy < 6 && y0+dy < height; dy++ {
+			for x := range width {
+				if idx := indices[(y0+dy)*width+x]; idx >= 0 && !slices.Contains(used, idx) {
+					used = append(used, idx)
+				}
+			}
+		}
+		slices.Sort(used)
+		for _, idx := range used {
+			for x := range width {
+				bits := 0
+				for dy := 0; dy < 6 && y0+dy < height; dy++ {
+					if indices[(y0+dy)*width+x] == idx {
+						bits |= 1 << dy
+					}
+				}
+				band[x] = byte(63 + bits)
+			}
+			fmt.Fprintf(&res, "#%d", idx)
+			writeSixelRLE(&res, band)
+			res.WriteByte('$') // back to the start of the band
+		}
+		res.WriteByte('-') // next band
+	}
+	res.WriteString("\033\\")
+	return res.String(), nil
+}
+
+// Writes sixels, compressing repeated ones.
+func writeSixelRLE(w *strings.Builder, band []byte) {
+	for i := 0; i < len(band); {
+		j := i
+		for j < len(band) && band[j] == band[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, band[i])
+		} else {
+			w.Write(band[i:j])
+		}
+		i = j
+	}
+}
+
+func percent(v uint8) int {
+	return (int(v)*100 + 127) / 255
+}
+
+type colorCount struct {
+	c color.RGBA
+	n int
+}
+
+// Splits color space into boxes of equal population along the widest channel,
+// until there are maxColors boxes or ctx is done. Palette is made of box averages.
+func medianCut(ctx context.Context, pixels []color.RGBA, maxColors int) []color.RGBA {
+	counts := map[color.RGBA]int{}
+	for _, c := range pixels {
+		counts[c]++
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	colors := make([]colorCount, 0, len(counts))
+	for c, n := range counts {
+		colors = append(colors, colorCount{c, n})
+	}
+	// Map iteration order is random, keeping result stable.
+	slices.SortFunc(colors, func(a, b colorCount) int { return colorKey(a.c) - colorKey(b.c) })
+
+	boxes := [][]colorCount{colors}
+	for len(boxes) < maxColors && ctx.Err() == nil {
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, rng := widestChannel(box)
+			if rng > bestRange {
+				best, bestChannel, bestRange = i, channel, rng
+			}
+		}
+		if best < 0 {
+			break // every box has a single color
+		}
+		box := boxes[best]
+		slices.SortStableFunc(box, func(a, b colorCount) int {
+			return int(channelOf(a.c, bestChannel)) - int(channelOf(b.c, bestChannel))
+		})
+		total := 0
+		for _, cc := range box {
+			total += cc.n
+		}
+		mid, acc := 1, box[0].n
+		for mid < len(box)-1 && acc+box[mid].n <= total/2 {
+			acc += box[mid].n
+			mid++
+		}
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	palette := make([]color.RGBA, 0, len(boxes))
+	for _, box := range boxes {
+		var sums [3]int
+		n := 0
+		for _, cc := range box {
+			sums[0] += int(cc.c.R) * cc.n
+			sums[1] += int(cc.c.G) * cc.n
+			sums[2] += int(cc.c.B) * cc.n
+			n += cc.n
+		}
+		palette = append(palette, color.RGBA{uint8(sums[0] / n), uint8(sums[1] / n), uint8(sums[2] / n), 0xff})
+	}
+	return palette
+}
+
+func colorKey(c color.RGBA) int {
+	return int(c.R)<<24 | int(c.G)<<16 | int(c.B)<<8 | int(c.A)
+}
+
+func widestChannel(box []colorCount) (int, int) {
+	lo := [3]uint8{255, 255, 255}
+	hi := [3]uint8{}
+	for _, cc := range box {
+		for ch := range 3 {
+			v := channelOf(cc.c, ch)
+			lo[ch] = min(lo[ch], v)
+			hi[ch] = max(hi[ch], v)
+		}
+	}
+	channel, rng := 0, 0
+	for ch := range 3 {
+		if r := int(hi[ch]) - int(lo[ch]); r > rng {
+			channel, rng = ch, r
+		}
+	}
+	return channel, rng
+}
+
+func channelOf(c color.RGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+func nearestColor(palette []color.RGBA, c color.RGBA) int {
+	best, bestDist := 0, -1
+	for i, p := range palette {
+		dr, dg, db := int(p.R)-int(c.R), int(p.G)-int(c.G), int(p.B)-int(c.B)
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
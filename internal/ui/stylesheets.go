@@ -25,8 +25,32 @@ type Stylesheet struct {
 	TreeSelectionArrow  lipgloss.Style
 	TreeIndent          lipgloss.Style
 	TreeIndentSelected  lipgloss.Style
+	TreeSearchMatch     lipgloss.Style
+
+	GitModified   lipgloss.Style
+	GitStaged     lipgloss.Style
+	GitUntracked  lipgloss.Style
+	GitIgnored    lipgloss.Style
+	GitConflicted lipgloss.Style
+
+	FindStatus lipgloss.Style
 
 	PlainTextPreview lipgloss.Style
+	CodePreview      lipgloss.Style
+	BinaryPreview    lipgloss.Style
+	FileType         lipgloss.Style
+	DirPreview       lipgloss.Style
+	DiffPreview      lipgloss.Style
+	DiffAdded        lipgloss.Style
+	DiffRemoved      lipgloss.Style
+	DiffHunk         lipgloss.Style
+	MarkdownPreview  lipgloss.Style
+	MarkdownHeading  lipgloss.Style
+	MarkdownCode     lipgloss.Style
+	MarkdownLink     lipgloss.Style
+	MarkdownQuote    lipgloss.Style
+	SyntaxTheme      string // chroma style name, see https://xyproto.github.io/splash/docs/
+	TabWidth         int    // columns, tabs in previews are expanded to
 }
 
 var DefaultStylesheet = Stylesheet{
@@ -59,6 +83,15 @@ var DefaultStylesheet = Stylesheet{
 	TreeSelectionArrow: lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")),
 	TreeIndent:         lipgloss.NewStyle().Foreground(lipgloss.Color("#363636")),
 	TreeIndentSelected: lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")),
+	TreeSearchMatch:    lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")).Underline(true),
+
+	GitModified:   lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")),
+	GitStaged:     lipgloss.NewStyle().Foreground(lipgloss.Color("#74AC6D")),
+	GitUntracked:  lipgloss.NewStyle().Foreground(lipgloss.Color("#6DACA4")),
+	GitIgnored:    lipgloss.NewStyle().Foreground(lipgloss.Color("#5c5c5c")),
+	GitConflicted: lipgloss.NewStyle().Foreground(lipgloss.Color("#AC6D74")).Bold(true),
+
+	FindStatus: lipgloss.NewStyle().Foreground(lipgloss.Color("#8c7ca6")),
 
 	PlainTextPreview: lipgloss.NewStyle().
 		Italic(true).
@@ -66,4 +99,36 @@ var DefaultStylesheet = Stylesheet{
 		BorderForeground(lipgloss.Color("#363636")).
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderLeft(true),
+	CodePreview: lipgloss.NewStyle().
+		BorderForeground(lipgloss.Color("#363636")).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true),
+	BinaryPreview: lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#a8a8a8")).
+		BorderForeground(lipgloss.Color("#363636")).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true),
+	FileType: lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")),
+	DirPreview: lipgloss.NewStyle().
+		BorderForeground(lipgloss.Color("#363636")).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true),
+	DiffPreview: lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#a8a8a8")).
+		BorderForeground(lipgloss.Color("#363636")).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true),
+	DiffAdded:   lipgloss.NewStyle().Foreground(lipgloss.Color("#74AC6D")),
+	DiffRemoved: lipgloss.NewStyle().Foreground(lipgloss.Color("#AC6D74")),
+	DiffHunk:    lipgloss.NewStyle().Foreground(lipgloss.Color("#8c7ca6")),
+	MarkdownPreview: lipgloss.NewStyle().
+		BorderForeground(lipgloss.Color("#363636")).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true),
+	MarkdownHeading: lipgloss.NewStyle().Foreground(lipgloss.Color("#6D74AC")).Bold(true),
+	MarkdownCode:    lipgloss.NewStyle().Foreground(lipgloss.Color("#ACA46D")),
+	MarkdownLink:    lipgloss.NewStyle().Foreground(lipgloss.Color("#6DACA4")).Underline(true),
+	MarkdownQuote:   lipgloss.NewStyle().Foreground(lipgloss.Color("#5c5c5c")),
+	SyntaxTheme:     "monokai",
+	TabWidth:        4,
 }
@@ -0,0 +1,139 @@
+package fuzzy
+
+import (
+	"math"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	scoreGapStart    = -3
+	scoreGapExtend   = -1
+	bonusConsecutive = 8
+	bonusBoundary    = 10
+	bonusCamel       = 8
+	bonusFirstChar   = 6
+
+	noMatch = math.MinInt / 2
+)
+
+// Match checks if all runes of pattern appear in str in the same order.
+// Returns score (higher is better) and indices of matched runes in str.
+// Matching is case-insensitive, unless pattern contains upper case letters.
+func Match(pattern, str string) (int, []int, bool) {
+	p := []rune(pattern)
+	s := []rune(str)
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+	caseSensitive := false
+	for _, r := range p {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	if !fits(p, s, eq) {
+		return 0, nil, false
+	}
+
+	// scores[i][j] is the best score of matching p[:i+1] with p[i] matched at s[j],
+	// from[i][j] is a position of p[i-1] in that match.
+	scores := make([][]int, len(p))
+	from := make([][]int, len(p))
+	for i := range p {
+		scores[i] = make([]int, len(s))
+		from[i] = make([]int, len(s))
+
+		// Best score of previous row, which ends before s[j-1], with a gap penalty applied.
+		gapBest, gapFrom := noMatch, -1
+		for j := range s {
+			scores[i][j] = noMatch
+			if i > 0 && j >= 2 {
+				if gapBest != noMatch {
+					gapBest += scoreGapExtend
+				}
+				if prev := scores[i-1][j-2]; prev != noMatch && prev+scoreGapStart > gapBest {
+					gapBest, gapFrom = prev+scoreGapStart, j-2
+				}
+			}
+			if !eq(p[i], s[j]) {
+				continue
+			}
+			matched := scoreMatch + bonusAt(s, j)
+			if i == 0 {
+				if j == 0 {
+					matched += bonusFirstChar
+				}
+				scores[i][j] = matched
+				from[i][j] = -1
+				continue
+			}
+			best, bestFrom := gapBest, gapFrom
+			if j >= 1 && scores[i-1][j-1] != noMatch && scores[i-1][j-1]+bonusConsecutive > best {
+				best, bestFrom = scores[i-1][j-1]+bonusConsecutive, j-1
+			}
+			if best == noMatch {
+				continue
+			}
+			scores[i][j] = best + matched
+			from[i][j] = bestFrom
+		}
+	}
+
+	last := len(p) - 1
+	end := -1
+	for j := range s {
+		if scores[last][j] != noMatch && (end < 0 || scores[last][j] > scores[last][end]) {
+			end = j
+		}
+	}
+	positions := make([]int, len(p))
+	for i, j := last, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+	// Shorter strings are better.
+	return scores[last][end] - len(s)/8, positions, true
+}
+
+func bonusAt(s []rune, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, cur := s[i-1], s[i]
+	switch {
+	case isSeparator(prev) && !isSeparator(cur):
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamel
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return bonusCamel
+	}
+	return 0
+}
+
+func isSeparator(r rune) bool {
+	switch r {
+	case '/', '_', '-', '.', ' ':
+		return true
+	}
+	return false
+}
+
+func fits(p, s []rune, eq func(a, b rune) bool) bool {
+	pi := 0
+	for si := 0; si < len(s) && pi < len(p); si++ {
+		if eq(p[pi], s[si]) {
+			pi++
+		}
+	}
+	return pi == len(p)
+}
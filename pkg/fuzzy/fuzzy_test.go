@@ -0,0 +1,40 @@
+package fuzzy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	_, positions, ok := Match("fb", "foo_bar")
+	require.True(t, ok)
+	require.Equal(t, []int{0, 4}, positions)
+
+	_, _, ok = Match("bf", "foo_bar")
+	require.False(t, ok)
+
+	_, _, ok = Match("FB", "foo_bar")
+	require.False(t, ok, "upper case pattern is case sensitive")
+
+	_, positions, ok = Match("", "anything")
+	require.True(t, ok)
+	require.Empty(t, positions)
+}
+
+func TestMatchRanking(t *testing.T) {
+	rank := func(pattern string, candidates ...string) {
+		scores := map[string]int{}
+		for _, c := range candidates {
+			score, _, ok := Match(pattern, c)
+			require.True(t, ok, c)
+			scores[c] = score
+		}
+		for i := 1; i < len(candidates); i++ {
+			require.Greater(t, scores[candidates[i-1]], scores[candidates[i]], "%s vs %s", candidates[i-1], candidates[i])
+		}
+	}
+	rank("tree", "tree.go", "tree_test.go", "the_rendered_element.go")
+	rank("rg", "render_gen.go", "rating.txt")
+	rank("main", "main.go", "my_domain_in.go")
+}
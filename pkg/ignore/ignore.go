@@ -0,0 +1,135 @@
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a single rule of an ignore file (.gitignore syntax).
+type Pattern struct {
+	base    string // directory of the ignore file, matched paths are relative to it
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Parse reads patterns of an ignore file, located in slash separated directory base.
+func Parse(r io.Reader, base string) []Pattern {
+	patterns := []Pattern{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p, ok := parseLine(scanner.Text(), base); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func parseLine(line, base string) (Pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+	p := Pattern{base: path.Clean(base)}
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+	// Patterns with a slash in the beginning or middle are relative to base, others match at any depth.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	re, err := regexp.Compile("^" + globToRegexp(line) + "$")
+	if err != nil {
+		return Pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// Spaces in the end are trimmed, unless they're escaped.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func globToRegexp(glob string) string {
+	res := strings.Builder{}
+	for i := 0; i < len(glob); i++ {
+		ch := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			res.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && i > 0 && glob[i-1] == '/':
+			res.WriteString(".*")
+			i++
+		case ch == '*':
+			res.WriteString("[^/]*")
+		case ch == '?':
+			res.WriteString("[^/]")
+		case ch == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				res.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			res.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case ch == '\\' && i+1 < len(glob):
+			i++
+			res.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			res.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return res.String()
+}
+
+// Reports, whether pattern applies to slash separated path.
+func (p Pattern) matches(name string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	rel, ok := strings.CutPrefix(name, p.base+"/")
+	if p.base == "/" {
+		rel, ok = strings.CutPrefix(name, "/")
+	}
+	return ok && p.re.MatchString(rel)
+}
+
+// Matcher combines patterns of several ignore files.
+// Patterns of deeper directories should follow patterns of their parents, so they take precedence.
+type Matcher []Pattern
+
+// Match reports, whether slash separated path is ignored. Last matching pattern decides.
+// Parent directories aren't checked, so path of an ignored directory is expected to never reach here.
+func (m Matcher) Match(name string, isDir bool) bool {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].matches(name, isDir) {
+			return !m[i].negate
+		}
+	}
+	return false
+}
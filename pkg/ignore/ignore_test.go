@@ -0,0 +1,73 @@
+package ignore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	m := Matcher(Parse(strings.NewReader(`
+# comment
+*.log
+!important.log
+build/
+/root_only.txt
+docs/*.md
+a/**/z
+logs/**
+\#hash
+[abc]?.txt
+[!x].bin
+trailing  
+`), "/repo"))
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"/repo/debug.log", false, true},
+		{"/repo/deep/nested/debug.log", false, true},
+		{"/repo/important.log", false, false},
+		{"/repo/build", true, true},
+		{"/repo/sub/build", true, true},
+		{"/repo/build", false, false},
+		{"/repo/root_only.txt", false, true},
+		{"/repo/sub/root_only.txt", false, false},
+		{"/repo/docs/readme.md", false, true},
+		{"/repo/docs/sub/readme.md", false, false},
+		{"/repo/a/z", false, true},
+		{"/repo/a/b/c/z", false, true},
+		{"/repo/logs/x/y", false, true},
+		{"/repo/#hash", false, true},
+		{"/repo/comment", false, false},
+		{"/repo/b1.txt", false, true},
+		{"/repo/d1.txt", false, false},
+		{"/repo/y.bin", false, true},
+		{"/repo/x.bin", false, false},
+		{"/repo/trailing", false, true},
+		{"/other/debug.log", false, false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.ignored, m.Match(c.path, c.isDir), c.path)
+	}
+}
+
+func TestMatchNested(t *testing.T) {
+	m := Matcher{}
+	m = append(m, Parse(strings.NewReader("*.gen\nvendor/\n"), "/repo")...)
+	m = append(m, Parse(strings.NewReader("!keep.gen\n/local\n"), "/repo/sub")...)
+
+	require.True(t, m.Match("/repo/sub/x.gen", false))
+	require.False(t, m.Match("/repo/sub/keep.gen", false), "deeper file takes precedence")
+	require.True(t, m.Match("/repo/keep.gen", false), "negation applies only below it's file")
+	require.True(t, m.Match("/repo/sub/local", false))
+	require.False(t, m.Match("/repo/local", false))
+	require.True(t, m.Match("/repo/sub/vendor", true))
+
+	root := Matcher(Parse(strings.NewReader("/top\n"), "/"))
+	require.True(t, root.Match("/top", false))
+	require.False(t, root.Match("/a/top", false))
+}